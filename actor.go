@@ -1,13 +1,16 @@
 package phony
 
 import (
+	"context"
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var stops = sync.Pool{New: func() interface{} { return make(chan struct{}, 1) }}
 var elems = sync.Pool{New: func() interface{} { return new(queueElem) }}
+var restartRaces atomic.Uint64 // times advance lost the race to shut down a worker
 
 // A message in the queue
 type queueElem struct {
@@ -21,55 +24,195 @@ type queueElem struct {
 // It is up to the user to ensure that memory is used safely, and that messages do not contain blocking operations.
 // An Inbox must not be copied after first use.
 type Inbox struct {
-	noCopy noCopy
-	head   *queueElem                // Used carefully to avoid needing atomics
-	tail   atomic.Pointer[queueElem] // *queueElem, accessed atomically
-	busy   atomic.Bool               // accessed atomically, 1 if sends should apply backpressure
+	noCopy    noCopy
+	head      *queueElem                      // Used carefully to avoid needing atomics
+	tail      atomic.Pointer[queueElem]       // *queueElem, accessed atomically
+	busy      atomic.Bool                     // accessed atomically, 1 if sends should apply backpressure
+	idle      atomic.Pointer[func()]          // accessed atomically, a message to run once the queue is empty
+	onStop    atomic.Pointer[func()]          // accessed atomically, the action to run once the Inbox is stopped and empty
+	stopped   atomic.Bool                     // accessed atomically, 1 if new messages should be dropped
+	limited   bool                            // true if created by NewInboxLimited, never modified after creation
+	trackGoID atomic.Bool                     // accessed atomically, 1 if the worker should record its goroutine ID
+	goid      atomic.Uint64                   // accessed atomically, the worker's goroutine ID, or 0 if unknown
+	throttled atomic.Uint32                   // accessed atomically, the number of throttled sends from this Inbox
+	reverse   atomic.Pointer[queueElem]       // accessed atomically, the marker for a pending DrainReverse
+	waits     atomic.Int32                    // accessed atomically, the number of backpressure waits queued in this Inbox
+	enqueued  atomic.Uint64                   // accessed atomically, the number of messages ever queued
+	processed atomic.Uint64                   // accessed atomically, the number of messages finished, never more than enqueued
+	hooks     atomic.Pointer[hooks]           // accessed atomically, optional callbacks, nil if none were ever set
+	capacity  atomic.Int64                    // accessed atomically, the bound checked by Reserve, 0 if unbounded
+	reserved  atomic.Int64                    // accessed atomically, slots reserved but not yet used by a send
+	cause     atomic.Pointer[causalLink]      // accessed atomically, the causal chain of the running message, if the cycle guard is on
+	peers     atomic.Pointer[peerSet]         // accessed atomically, the Inboxes this one has sent to, nil unless TrackPeers was called
+	limiter   atomic.Pointer[senderLimiter]   // accessed atomically, per-sender rate limits, nil unless SetPerSenderLimit was called
+	threshold atomic.Int64                    // accessed atomically, 1 more than the Inbox's own backpressure threshold, 0 to use the package default
+	sched     atomic.Pointer[Scheduler]       // accessed atomically, the Scheduler that runs this Inbox's workers, nil for the default
+	urgent    atomic.Pointer[queueElem]       // accessed atomically, a stack of messages sent with ActPriority, newest first
+	overflow  atomic.Uint32                   // accessed atomically, the OverflowPolicy for a bounded Inbox
+	dropped   atomic.Uint64                   // accessed atomically, messages discarded by the OverflowPolicy
+	maxRun    atomic.Int64                    // accessed atomically, the number of messages a worker runs before yielding, 0 for no limit
+	ctx       atomic.Pointer[context.Context] // accessed atomically, the context of the ActCtx message that's running, if any
 }
 
 // Actor is the interface for Actors, based on their ability to receive a message from another Actor.
 // It's meant so that structs which embed an Inbox can satisfy a mutually compatible interface for message passing.
 type Actor interface {
 	Act(Actor, func())
-	enqueue(func())
+	inbox() *Inbox
+	enqueue(func()) bool
 	restart()
 	advance() bool
 }
 
-// enqueue puts a message into the Inbox and returns true if backpressure should be applied.
+// enqueue puts a message into the Inbox and returns true if it started a new worker.
 // If the inbox was empty, then the actor was not already running, so enqueue starts it.
-func (a *Inbox) enqueue(msg func()) {
+// It's only used for the Inbox's own messages, such as Block's, which are pinned so that a reverse drain leaves them in place.
+func (a *Inbox) enqueue(msg func()) (started bool) {
+	q := a.newElem(msg)
+	q.msg = pinned(q.msg)
+	return a.push(q)
+}
+
+// newElem wraps msg in a queueElem from the pool, timestamped if there's an age sink.
+func (a *Inbox) newElem(msg func()) *queueElem {
+	if h := a.hooks.Load(); h != nil && h.age != nil {
+		msg = a.stamp(msg)
+	}
 	q := elems.Get().(*queueElem)
 	*q = queueElem{msg: msg}
-	tail := a.tail.Swap(q)
+	return q
+}
+
+// push implements enqueue for a message that's already been wrapped in a queueElem.
+func (a *Inbox) push(q *queueElem) (started bool) {
+	return a.pushBatch(q, q, 1)
+}
+
+// pushBatch appends n already linked queueElems, from first to last, to the queue with a single swap of the tail, so they land next to each other.
+func (a *Inbox) pushBatch(first, last *queueElem, n uint64) (started bool) {
+	if o := observer.Load(); o != nil && o.enqueue != nil {
+		for i := uint64(0); i < n; i++ {
+			o.enqueue(a)
+		}
+	}
+	// Count the messages before they can run, so processed never overtakes enqueued
+	a.enqueued.Add(n)
+	tail := a.tail.Swap(last)
 	if tail != nil {
-		//An old tail exists, so update its next pointer to reference q
-		tail.next.Store(q)
+		//An old tail exists, so update its next pointer to reference first
+		tail.next.Store(first)
 	} else {
 		// No old tail existed, so no worker is currently running
-		// Update the head to point to q, then start the worker
-		a.head = q
+		// Update the head to point to first, then start the worker
+		a.head = first
 		a.restart()
+		started = true
 	}
+	return
 }
 
 // Act adds a message to an Inbox, which will be executed by the inbox's Actor at some point in the future.
 // When one Actor sends a message to another, the sender is meant to provide itself as the first argument to this function.
-// If the sender argument is non-nil and the receiving Inbox has been flooded, then backpressure is applied to the sender.
+// If the sender argument is non-nil and the receiving Inbox has been flooded, meaning it's busy and holds more messages than the threshold set by SetBackpressureThreshold, then backpressure is applied to the sender.
 // This backpressue cause the sender stop processing messages at some point in the future until the receiver has caught up with the sent message.
 // A nil first argument is valid, but should only be used in cases where backpressure is known to be unnecessary, such as when an Actor sends a message to itself or sends a response to a request (where it's the request sender's fault if they're flooded by responses).
+// An Actor that sends a message to itself never has backpressure applied, whether it passes itself or nil as the sender, and its messages to itself run in the order they were sent, interleaved with other senders' messages but never reordered among themselves.
 func (a *Inbox) Act(from Actor, action func()) {
+	a.act(from, action)
+}
+
+// ActStarted is like Act, but returns true if the message started a new worker because the Inbox was idle, or false if the message was added to an already running Actor.
+// This is meant for profiling how often Actors go idle and restart, and the result is inherently racy.
+func (a *Inbox) ActStarted(from Actor, action func()) bool {
+	return a.act(from, action)
+}
+
+// ActChain is like Act, but action may return a follow-up message, which is then sent to the same Inbox without backpressure.
+// This makes multi-step state transitions explicit without nesting closures.
+// Messages that were already queued run between the two steps, so each step should leave the Actor in a consistent state.
+func (a *Inbox) ActChain(from Actor, action func() (next func())) {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	a.Act(from, func() {
+		if next := action(); next != nil {
+			a.Act(nil, next)
+		}
+	})
+}
+
+// act implements Act and ActStarted.
+func (a *Inbox) act(from Actor, action func()) (started bool) {
+	started, sent := a.send(from, action)
+	if sent {
+		a.pressure(from)
+	}
+	return
+}
+
+// send implements act up to, but not including, backpressure, and returns false for sent if the message was dropped.
+func (a *Inbox) send(from Actor, action func()) (started, sent bool) {
+	return a.sendDroppable(from, action, nil)
+}
+
+// sendDroppable is like send, but calls dropped, on the Inbox's worker, if the DropOldest policy skips the message after it was queued.
+func (a *Inbox) sendDroppable(from Actor, action, dropped func()) (started, sent bool) {
 	if action == nil {
 		panic("tried to send nil action")
 	}
-	a.enqueue(action)
-	if from != nil && a.busy.Load() {
-		done := stops.Get().(chan struct{})
-		a.enqueue(func() { done <- struct{}{} })
-		from.enqueue(func() {
-			<-done
-			stops.Put(done)
-		})
+	if !a.admit(from, 1, false) {
+		return false, false
+	}
+	q := a.prepare(from, action, false, dropped)
+	if q == nil {
+		return false, false
+	}
+	started = a.push(q)
+	a.admitted(from, 1)
+	return started, true
+}
+
+// admit makes the checks that every way of sending n messages from from makes before queueing them, and returns false if the messages should be dropped.
+// Urgent messages, from ActPriority, skip the overflow policy.
+func (a *Inbox) admit(from Actor, n int, urgent bool) bool {
+	if a.stopped.Load() {
+		return false
+	}
+	if !urgent && a.overflows(n) {
+		return false
+	}
+	if from != nil {
+		if l := a.limiter.Load(); l != nil && !l.allow(from.inbox(), n) {
+			return false
+		}
+	}
+	return true
+}
+
+// prepare wraps an admitted action in a queueElem, applying the cycle guard, and returns nil if the message should be dropped.
+// If the DropOldest policy skips the message later, then dropped is called instead, if it isn't nil.
+func (a *Inbox) prepare(from Actor, action func(), urgent bool, dropped func()) *queueElem {
+	if from != nil && cycleGuard.Load() != nil {
+		if action = a.guardCycle(from, action); action == nil {
+			return nil
+		}
+	}
+	q := a.newElem(action)
+	if !urgent && a.trims() {
+		q.msg = a.droppable(q.msg, dropped)
+	}
+	return q
+}
+
+// admitted does the bookkeeping for n messages from from that have been queued.
+func (a *Inbox) admitted(from Actor, n int) {
+	if from != nil {
+		if peers := from.inbox().peers.Load(); peers != nil {
+			peers.add(a)
+		}
+	}
+	if a.reserved.Load() > 0 {
+		a.Release(n)
 	}
 }
 
@@ -77,12 +220,20 @@ func (a *Inbox) Act(from Actor, action func()) {
 // It then blocks until the Actor has finished running the provided function.
 // Block meant exclusively as a convenience function for non-Actor code to send messages and wait for responses.
 // If an Actor calls Block, then it may cause a deadlock, so Act should always be used instead.
+// An Actor that calls Block on itself always deadlocks, and SetBlockCheck can be used in tests to turn that, and any other Block from an Actor, into a panic.
+// If the Actor has been stopped, then Block returns immediately without running the action.
 func Block(actor Actor, action func()) {
 	if actor == nil {
 		panic("tried to send to nil actor")
 	} else if action == nil {
 		panic("tried to send nil action")
 	}
+	if blockCheck.Load() {
+		checkBlock(actor)
+	}
+	if actor.inbox().stopped.Load() {
+		return
+	}
 	done := stops.Get().(chan struct{})
 	actor.enqueue(action)
 	actor.enqueue(func() { done <- struct{}{} })
@@ -90,19 +241,149 @@ func Block(actor Actor, action func()) {
 	stops.Put(done)
 }
 
+// ActWhenIdle schedules a low priority message, which the Inbox will run the next time it finds its queue empty.
+// This is meant for opportunistic housekeeping, which shouldn't delay any real work.
+// Only one such message is pending at a time, so calling ActWhenIdle again before it runs replaces the previous action.
+// Messages which race with the queue emptying may still run before the idle action does.
+func (a *Inbox) ActWhenIdle(action func()) {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	a.idle.Store(&action)
+	// Send an empty message, so an idle worker wakes up and notices the action
+	a.enqueue(func() {})
+}
+
+// Stop closes the Inbox to new messages.
+// Messages which were already queued still run, but later calls to Act are silently dropped, and Block returns without running its action.
+// If the Inbox was created by NewInboxLimited, then Stop also releases its slot.
+// It is safe to call Stop more than once.
+func (a *Inbox) Stop() {
+	if !a.stopped.CompareAndSwap(false, true) {
+		return
+	}
+	if a.limited {
+		liveActors.Add(-1)
+	}
+	if a.onStop.Load() != nil {
+		// Send an empty message, so an idle worker wakes up and runs the action
+		a.enqueue(func() {})
+	}
+}
+
+// SetOnStop sets an action to run once the Inbox has been stopped and every message queued before that has finished.
+// It runs on the Inbox's worker, exactly once, no matter how many times Stop is called, so it's the place to release resources or notify other Actors that this one is gone.
+// Messages from an Act which raced with Stop, by starting before it, may still be queued after the action has run.
+// Calling SetOnStop again before the action runs replaces it, and calling it after the Inbox was stopped schedules the action to run as soon as the Inbox is empty.
+func (a *Inbox) SetOnStop(action func()) {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	a.onStop.Store(&action)
+	if a.stopped.Load() {
+		a.enqueue(func() {})
+	}
+}
+
+// inbox returns the Inbox itself, so package functions can reach the Inbox embedded in any Actor.
+func (a *Inbox) inbox() *Inbox {
+	return a
+}
+
 // run is executed when a message is placed in an empty Inbox, and launches a worker goroutine.
 // The worker goroutine processes messages from the Inbox until empty, and then exits.
 func (a *Inbox) run() {
 	a.busy.Store(true)
+	var goid uint64
+	track, check := a.trackGoID.Load(), blockCheck.Load()
+	if track || check {
+		goid = curGoID()
+	}
+	if track {
+		a.goid.Store(goid)
+	}
+	var outer interface{} // the Inbox whose worker this one is nested in, when running inline in sync mode
+	if check {
+		outer, _ = workers.Load(goid)
+		workers.Store(goid, a)
+	}
+	maxRun, count := a.maxRun.Load(), int64(0)
+	if syncMode.Load() {
+		// Yielding would restart the worker inline, nesting a new call to run for every maxRun messages, so run to empty instead
+		maxRun = 0
+	}
 	for running := true; running; running = a.advance() {
-		a.head.msg()
+		if maxRun > 0 {
+			if count == maxRun {
+				// Hand the rest of the queue to a new worker, which waits its turn with the Scheduler
+				// The queue isn't empty, so the Inbox stays busy, and no sender tries to start a worker of its own
+				a.yield()
+				break
+			}
+			count++
+		}
+		if a.urgent.Load() != nil {
+			a.runUrgent()
+		}
+		a.runMsg(a.head)
+	}
+	if track {
+		// Only clear our own ID, in case a new worker has already started
+		a.goid.CompareAndSwap(goid, 0)
+	}
+	if check {
+		if outer != nil {
+			workers.Store(goid, outer)
+		} else {
+			workers.Delete(goid)
+		}
+	}
+}
+
+// runMsg runs the message held by q, along with any hooks.
+func (a *Inbox) runMsg(q *queueElem) {
+	h := a.hooks.Load()
+	if h == nil {
+		runHandled(q.msg, nil)
+		return
+	}
+	if h.timing != nil {
+		start := time.Now()
+		runHandled(q.msg, h.panic)
+		h.timing(start, time.Now())
+		return
 	}
+	runHandled(q.msg, h.panic)
 }
 
 // returns true if we still have more work to do
 func (a *Inbox) advance() (more bool) {
 	head := a.head
+	a.processed.Add(1)
+	if o := observer.Load(); o != nil && o.dequeue != nil {
+		o.dequeue(a)
+	}
+	if marker := a.reverse.Load(); marker != nil {
+		a.reverse.Store(nil)
+		if head != marker {
+			a.reverseUntil(head, marker)
+		}
+	}
 	a.head = head.next.Load()
+	if a.head == nil && a.idle.Load() != nil {
+		if idle := a.idle.Swap(nil); idle != nil {
+			// The queue looks empty, so append the idle action and look again
+			a.enqueue(*idle)
+			a.head = head.next.Load()
+		}
+	}
+	if a.head == nil && a.onStop.Load() != nil && a.stopped.Load() {
+		if onStop := a.onStop.Swap(nil); onStop != nil {
+			// The queue has drained after a Stop, so append the final action
+			a.enqueue(*onStop)
+			a.head = head.next.Load()
+		}
+	}
 	if a.head == nil {
 		// We loaded the last message
 		// Unset busy and CAS the tail to nil to shut down
@@ -111,6 +392,7 @@ func (a *Inbox) advance() (more bool) {
 			// Someone pushed to the list before we could CAS the tail to shut down
 			// This means we're effectively restarting at this point
 			// Set busy and load the next message
+			restartRaces.Add(1)
 			a.busy.Store(true)
 			for a.head == nil {
 				// Busy loop until the message is successfully loaded
@@ -128,8 +410,32 @@ func (a *Inbox) advance() (more bool) {
 	return
 }
 
+// RestartRaces returns the number of times, across all Inboxes, that a worker found its queue empty but lost the race to shut down because a new message was being added.
+// When this happens, the worker busy loops until the new message is linked in, so a rapidly growing count is a sign of contention that hurts latency.
+// It's purely diagnostic.
+func RestartRaces() uint64 {
+	return restartRaces.Load()
+}
+
 func (a *Inbox) restart() {
-	go a.run()
+	if syncMode.Load() {
+		a.run()
+		return
+	}
+	a.scheduler().schedule(a.run, false)
+}
+
+// yield hands the rest of the Inbox's queue to a new worker, which waits its turn behind other Inboxes on a Scheduler with a worker limit.
+func (a *Inbox) yield() {
+	a.scheduler().schedule(a.run, true)
+}
+
+// scheduler returns the Scheduler that runs the Inbox's workers.
+func (a *Inbox) scheduler() *Scheduler {
+	if s := a.sched.Load(); s != nil {
+		return s
+	}
+	return defaultScheduler
 }
 
 // noCopy implements the sync.Locker interface, so go vet can catch unsafe copying
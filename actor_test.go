@@ -1,14 +1,33 @@
 package phony
 
 import (
+	"runtime"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 	"unsafe"
 )
 
+// hold blocks a's worker on a message, returning once it's running, so tests can queue up messages behind it until release is called.
+func hold(a Actor) (release func()) {
+	started, gate := make(chan struct{}), make(chan struct{})
+	a.Act(nil, func() {
+		close(started)
+		<-gate
+	})
+	<-started
+	return func() { close(gate) }
+}
+
 func TestInboxSize(t *testing.T) {
 	var a Inbox
 	var q queueElem
 	t.Logf("Inbox size: %d, message size: %d", unsafe.Sizeof(a), unsafe.Sizeof(q))
+	// Per-message options, such as age timestamps, wrap the closure instead of adding to every message
+	if size, word := unsafe.Sizeof(q), unsafe.Sizeof(uintptr(0)); size != 2*word {
+		t.Errorf("message size is %d, expected 2 words (%d bytes)", size, 2*word)
+	}
 }
 
 func TestBlock(t *testing.T) {
@@ -150,6 +169,76 @@ func BenchmarkSendChannel(b *testing.B) {
 	<-done
 }
 
+// fanInSenders are the numbers of concurrent senders used by the fan-in benchmarks.
+// Contention on the Inbox's tail only shows up when senders run on separate cores, so these are meant to be run on a multi-core machine, with e.g. -cpu 1,4,16.
+var fanInSenders = []int{1, 4, 16, 64}
+
+func BenchmarkFanInActor(b *testing.B) {
+	for _, senders := range fanInSenders {
+		b.Run(strconv.Itoa(senders), func(b *testing.B) {
+			benchmarkFanIn(b, senders)
+		})
+	}
+}
+
+func BenchmarkFanInChannel(b *testing.B) {
+	for _, senders := range fanInSenders {
+		b.Run(strconv.Itoa(senders), func(b *testing.B) {
+			done := make(chan struct{})
+			ch := make(chan func())
+			go func() {
+				for f := range ch {
+					f()
+				}
+				close(done)
+			}()
+			var wg sync.WaitGroup
+			f := func() {}
+			for idx := 0; idx < senders; idx++ {
+				n := b.N / senders
+				if idx < b.N%senders {
+					n++
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for ; n > 0; n-- {
+						ch <- f
+					}
+				}()
+			}
+			wg.Wait()
+			close(ch)
+			<-done
+		})
+	}
+}
+
+// BenchmarkManyActors sends a handful of messages to each of b.N short-lived Actors, and reports the peak number of goroutines while they run.
+// Reusing parked workers can only show up when they're free while the senders are still running, so like the fan-in benchmarks it's meant to be run on a multi-core machine, with e.g. -cpu 1,4,16.
+func BenchmarkManyActors(b *testing.B) {
+	const messages = 4
+	var wg sync.WaitGroup
+	wg.Add(b.N)
+	actors := make([]Inbox, b.N)
+	peak := runtime.NumGoroutine()
+	b.ResetTimer()
+	for idx := range actors {
+		a := &actors[idx]
+		for n := 1; n < messages; n++ {
+			a.Act(nil, func() {})
+		}
+		a.Act(nil, wg.Done)
+		if idx%1024 == 0 {
+			if n := runtime.NumGoroutine(); n > peak {
+				peak = n
+			}
+		}
+	}
+	wg.Wait()
+	b.ReportMetric(float64(peak), "peak-goroutines")
+}
+
 func BenchmarkRequestResponseActor(b *testing.B) {
 	var pinger, ponger Inbox
 	done := make(chan struct{})
@@ -218,3 +307,209 @@ func BenchmarkBlock(b *testing.B) {
 		Block(&a, func() {})
 	}
 }
+
+func TestActWhenIdle(t *testing.T) {
+	var a Inbox
+	var results []int
+	release := hold(&a)
+	done := make(chan struct{})
+	a.ActWhenIdle(func() { results = append(results, -1) })
+	a.ActWhenIdle(func() {
+		results = append(results, 1024)
+		close(done)
+	})
+	for idx := 0; idx < 1024; idx++ {
+		n := idx // Because idx gets mutated in place
+		a.Act(nil, func() { results = append(results, n) })
+	}
+	release()
+	<-done
+	if len(results) != 1025 {
+		t.Fatalf("ran %d messages, expected 1025", len(results))
+	}
+	for idx, n := range results {
+		if n != idx {
+			t.Errorf("value %d != index %d", n, idx)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	var a Inbox
+	var results []int
+	release := hold(&a)
+	for idx := 0; idx < 1024; idx++ {
+		n := idx // Because idx gets mutated in place
+		a.Act(nil, func() { results = append(results, n) })
+	}
+	done := make(chan struct{})
+	a.Act(nil, func() { close(done) })
+	a.Stop()
+	a.Act(nil, func() { results = append(results, -1) })
+	release()
+	<-done
+	Block(&a, func() { results = append(results, -1) })
+	if len(results) != 1024 {
+		t.Errorf("ran %d messages, expected 1024", len(results))
+	}
+}
+
+func TestSetOnStop(t *testing.T) {
+	var a Inbox
+	var results []int
+	stopped := make(chan []int, 2)
+	a.SetOnStop(func() { stopped <- results })
+	release := hold(&a)
+	for idx := 0; idx < 1024; idx++ {
+		n := idx // Because idx gets mutated in place
+		a.Act(nil, func() { results = append(results, n) })
+	}
+	var wg sync.WaitGroup
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.Stop()
+		}()
+	}
+	wg.Wait()
+	release()
+	if final := <-stopped; len(final) != 1024 {
+		t.Errorf("stop action ran after %d messages, expected 1024", len(final))
+	}
+	a.Stop()
+	select {
+	case <-stopped:
+		t.Errorf("stop action ran more than once")
+	case <-time.After(10 * time.Millisecond):
+	}
+	// An idle Inbox should still run the action when stopped
+	var b Inbox
+	done := make(chan struct{})
+	b.SetOnStop(func() { close(done) })
+	b.Stop()
+	<-done
+}
+
+func TestActStarted(t *testing.T) {
+	var a Inbox
+	started, gate := make(chan struct{}), make(chan struct{})
+	if !a.ActStarted(nil, func() {
+		close(started)
+		<-gate
+	}) {
+		t.Errorf("send to idle inbox didn't start a worker")
+	}
+	<-started
+	if a.ActStarted(nil, func() {}) {
+		t.Errorf("send to running inbox started a worker")
+	}
+	close(gate)
+}
+
+func TestActChain(t *testing.T) {
+	var a Inbox
+	var results []string
+	release := hold(&a)
+	a.ActChain(nil, func() func() {
+		results = append(results, "first")
+		return func() { results = append(results, "second") }
+	})
+	a.ActChain(nil, func() func() {
+		results = append(results, "other")
+		return nil
+	})
+	release()
+	Block(&a, func() {})
+	Block(&a, func() {}) // In case the follow-up was sent after the first Block
+	if len(results) != 3 || results[0] != "first" || results[1] != "other" || results[2] != "second" {
+		t.Errorf("unexpected order %v", results)
+	}
+}
+
+func TestRestartRaces(t *testing.T) {
+	if runtime.GOMAXPROCS(0) == 1 {
+		t.Skip("the race needs a sender running alongside the worker")
+	}
+	var a Inbox
+	before := RestartRaces()
+	const senders, count = 4, 1024
+	var ran int
+	// The race is down to timing, so keep sending rounds until it's been lost at least once
+	for rounds, deadline := 0, time.Now().Add(5*time.Second); RestartRaces() == before; rounds++ {
+		if time.Now().After(deadline) {
+			t.Fatalf("no restart races in %d rounds of %d senders", rounds, senders)
+		}
+		var wg sync.WaitGroup
+		for idx := 0; idx < senders; idx++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for idx := 0; idx < count; idx++ {
+					a.Act(nil, func() { ran++ })
+				}
+			}()
+		}
+		wg.Wait()
+		Block(&a, func() {})
+		if ran != (rounds+1)*senders*count {
+			t.Fatalf("ran %d messages, expected %d", ran, (rounds+1)*senders*count)
+		}
+	}
+	t.Logf("Restart races: %d", RestartRaces()-before)
+}
+
+func TestLen(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	for idx := 0; idx < 1024; idx++ {
+		a.Act(nil, func() {})
+	}
+	if n := a.Len(); n != 1025 {
+		t.Errorf("length %d with 1025 messages queued", n)
+	}
+	if !a.Busy() {
+		t.Errorf("running inbox isn't busy")
+	}
+	release()
+	Block(&a, func() {})
+	if n := a.Processed(); n < 1026 {
+		t.Errorf("processed %d messages, expected at least 1026", n)
+	}
+}
+
+func TestLenDrains(t *testing.T) {
+	var a Inbox
+	var wg sync.WaitGroup
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1024; n++ {
+				a.Act(nil, func() {})
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for sampling := true; sampling; {
+		select {
+		case <-done:
+			sampling = false
+		default:
+		}
+		if n := a.Len(); n < 0 {
+			t.Fatalf("length %d is negative", n)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for a.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("length %d after draining, expected 0", a.Len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,42 @@
+package phony
+
+// AuditEntry describes one message forwarded by an AuditActor.
+type AuditEntry struct {
+	Seq     uint64 // Position of the message in the forwarded order, starting from 1
+	Tag     string // Kind of message, as given by the sender
+	Payload any    // Data the message carries, as given by the sender
+}
+
+// AuditActor is an Actor that records each message it's sent to an audit sink before forwarding it to a target Actor.
+// Recording and forwarding both happen on the AuditActor's own worker, so the sink sees entries in exactly the order the target receives the messages.
+// Messages are forwarded with the AuditActor as the sender, so a slow target applies backpressure to the AuditActor, which passes it on to the original senders.
+type AuditActor struct {
+	Inbox
+	target Actor
+	sink   func(AuditEntry)
+	seq    uint64
+}
+
+// NewAuditActor returns a new AuditActor which forwards to target, passing an AuditEntry to sink for each message.
+// The sink runs on the AuditActor's worker, so it should be fast, and it must not Block.
+func NewAuditActor(target Actor, sink func(entry AuditEntry)) *AuditActor {
+	if target == nil {
+		panic("tried to send to nil actor")
+	} else if sink == nil {
+		panic("tried to send nil action")
+	}
+	return &AuditActor{target: target, sink: sink}
+}
+
+// Send records a message, described by tag and payload, and then forwards action to the target.
+// The tag and payload are only used for the audit entry, so messages that leave them empty still run, but produce entries that say nothing about what was done.
+func (a *AuditActor) Send(from Actor, tag string, payload any, action func()) {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	a.Act(from, func() {
+		a.seq++
+		a.sink(AuditEntry{Seq: a.seq, Tag: tag, Payload: payload})
+		a.target.Act(a, action)
+	})
+}
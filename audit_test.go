@@ -0,0 +1,28 @@
+package phony
+
+import "testing"
+
+func TestAuditActor(t *testing.T) {
+	var target Inbox
+	var entries []AuditEntry
+	var processed []int
+	audit := NewAuditActor(&target, func(entry AuditEntry) { entries = append(entries, entry) })
+	var sender Inbox
+	for idx := 0; idx < 1024; idx++ {
+		n := idx // Because idx gets mutated in place
+		sender.Act(nil, func() {
+			audit.Send(&sender, "append", n, func() { processed = append(processed, n) })
+		})
+	}
+	Block(&sender, func() {})
+	Block(audit, func() {})
+	Block(&target, func() {})
+	if len(entries) != len(processed) || len(entries) != 1024 {
+		t.Fatalf("recorded %d entries and processed %d messages, expected 1024", len(entries), len(processed))
+	}
+	for idx, entry := range entries {
+		if entry.Seq != uint64(idx+1) || entry.Tag != "append" || entry.Payload != processed[idx] {
+			t.Errorf("entry %d was %+v, but the target processed %d", idx, entry, processed[idx])
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package phony
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBackpressureThreshold is the default for SetBackpressureThreshold, the number of messages a busy Inbox may hold before it counts as flooded.
+const DefaultBackpressureThreshold = 32
+
+var backpressureThreshold atomic.Int64                            // set to DefaultBackpressureThreshold by init
+var backpressureBatch atomic.Uint32                               // 0 or 1 means every throttled send applies backpressure
+var releaseHook atomic.Pointer[func(Actor, Actor, time.Duration)] // nil means no hook
+var backpressureTimeout atomic.Int64                              // a time.Duration, 0 means wait forever
+var backpressureTimeouts atomic.Uint64                            // pauses that ended because of the timeout
+
+func init() {
+	backpressureThreshold.Store(DefaultBackpressureThreshold)
+}
+
+// SetBackpressureThreshold sets the number of messages a busy Inbox may hold, counting the one that's running, before it's considered flooded, so further sends to it apply backpressure to their senders.
+// Below the threshold, Actors that trade messages quickly don't pause each other for every message, and above it, a sender can't keep growing the receiver's queue.
+// A threshold of 0 applies backpressure on every send to a busy Inbox, which was the behavior before thresholds existed.
+// Only the decision to apply backpressure changes, so the ordering of messages is unaffected.
+func SetBackpressureThreshold(n int) {
+	if n < 0 {
+		n = 0
+	}
+	backpressureThreshold.Store(int64(n))
+}
+
+// SetBackpressureBatch makes senders apply backpressure only once for every n sends to a flooded Inbox, instead of for each one.
+// This reduces the overhead of tight sender loops, at the cost of letting each sender queue up to n messages per pause instead of 1.
+// An n of 1 or less (the default) applies backpressure on every throttled send.
+func SetBackpressureBatch(n int) {
+	if n < 1 {
+		n = 0
+	}
+	backpressureBatch.Store(uint32(n))
+}
+
+// SetBackpressureReleaseHook sets a function to call each time a sender finishes a backpressure pause, because the flooded receiver caught up.
+// The waited duration is measured from when the send was throttled until the sender's pause ended, which shows how long senders spend throttled.
+// The receiver argument is the receiving Actor's Inbox, rather than the struct it's embedded in.
+// The hook runs on the sender's worker, so it must be fast and non-blocking, and passing nil removes it.
+func SetBackpressureReleaseHook(hook func(sender, receiver Actor, waited time.Duration)) {
+	if hook == nil {
+		releaseHook.Store(nil)
+		return
+	}
+	releaseHook.Store(&hook)
+}
+
+// SetBackpressureTimeout limits how long a sender stays paused by backpressure, as a backstop against deadlocks between Actors that flood each other.
+// A sender whose pause reaches the timeout resumes without waiting for the receiver to catch up, and the event is counted by BackpressureTimeouts.
+// A d of 0 or less (the default) means senders wait for as long as it takes.
+func SetBackpressureTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	backpressureTimeout.Store(int64(d))
+}
+
+// BackpressureTimeouts returns the number of backpressure pauses, across all Inboxes, that were cut short by the timeout set with SetBackpressureTimeout.
+// A growing count means some receiver is stuck or far too slow, and is worth investigating.
+func BackpressureTimeouts() uint64 {
+	return backpressureTimeouts.Load()
+}
+
+// pressure applies backpressure to from, if there is a sender, the Inbox is flooded, and the throttle allows it.
+// An Actor sending to itself is exempt, since pausing until it catches up with its own message would only add messages to its queue without slowing anything down.
+func (a *Inbox) pressure(from Actor) {
+	if from == nil || !a.flooded() {
+		return
+	}
+	if sender := from.inbox(); sender != a && sender.throttle() {
+		a.backpressure(from)
+	}
+}
+
+// backpressure makes from pause, at some point in the future, until the Inbox has caught up with the message that was just sent.
+// A message is sent to the Inbox to signal a channel, and the sender is sent a message that waits on that channel.
+func (a *Inbox) backpressure(from Actor) {
+	sender := from.inbox()
+	sender.waits.Add(1)
+	hook := releaseHook.Load()
+	var start time.Time
+	if hook != nil {
+		start = time.Now()
+	}
+	done := stops.Get().(chan struct{})
+	a.enqueue(func() { done <- struct{}{} })
+	from.enqueue(func() {
+		defer sender.waits.Add(-1)
+		if !wait(done) {
+			return
+		}
+		stops.Put(done)
+		if hook != nil {
+			(*hook)(from, a, time.Since(start))
+		}
+	})
+}
+
+// wait waits for a signal on done, and returns false if the backpressure timeout expired first.
+// After a timeout, the late signal lands in done's buffer, so the channel must not go back into the pool.
+func wait(done chan struct{}) bool {
+	timeout := time.Duration(backpressureTimeout.Load())
+	if timeout <= 0 {
+		<-done
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		backpressureTimeouts.Add(1)
+		return false
+	}
+}
+
+// SetBackpressureThreshold overrides the package-wide threshold, set with the SetBackpressureThreshold function, for this Inbox alone.
+// A high threshold suits an Actor that receives bursts from fast producers and can work through them, while 0 makes every send to it while it's busy apply backpressure.
+// A negative n removes the override, so the package-wide threshold applies again.
+func (a *Inbox) SetBackpressureThreshold(n int) {
+	if n < 0 {
+		a.threshold.Store(0)
+		return
+	}
+	a.threshold.Store(int64(n) + 1)
+}
+
+// flooded returns true if sends to the Inbox should apply backpressure, because it's busy with more than the threshold number of messages.
+func (a *Inbox) flooded() bool {
+	if !a.busy.Load() {
+		return false
+	}
+	threshold := a.threshold.Load() - 1
+	if threshold < 0 {
+		threshold = backpressureThreshold.Load()
+	}
+	return int64(a.Len()) > threshold && !syncMode.Load()
+}
+
+// throttle counts a throttled send from this Inbox, and returns true if backpressure should be applied for it.
+func (a *Inbox) throttle() bool {
+	n := backpressureBatch.Load()
+	if n <= 1 {
+		return true
+	}
+	return a.throttled.Add(1)%n == 0
+}
+
+// PendingBackpressure returns the number of backpressure waits that this Inbox, as a sender, has queued for itself and not yet finished.
+// Each one is a pause until some flooded receiver catches up, so a high count means the Actor is sending to many slow receivers.
+// The result is inherently racy, and is meant as an overload signal for monitoring.
+func (a *Inbox) PendingBackpressure() int {
+	return int(a.waits.Load())
+}
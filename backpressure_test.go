@@ -0,0 +1,324 @@
+package phony
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// eagerBackpressure makes every send to a busy Inbox apply backpressure, for tests that need a single message to trigger it, and returns a function that restores the default threshold.
+func eagerBackpressure() func() {
+	SetBackpressureThreshold(0)
+	return func() { SetBackpressureThreshold(DefaultBackpressureThreshold) }
+}
+
+func TestBackpressureBatch(t *testing.T) {
+	defer eagerBackpressure()()
+	SetBackpressureBatch(4)
+	defer SetBackpressureBatch(0)
+	var sender, receiver Inbox
+	release := hold(&receiver)
+	Block(&sender, func() {
+		for idx := 0; idx < 3; idx++ {
+			receiver.Act(&sender, func() {})
+		}
+	})
+	ran := make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatalf("sender paused before reaching the batch size")
+	}
+	Block(&sender, func() {
+		receiver.Act(&sender, func() {})
+	})
+	ran = make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+		t.Errorf("sender didn't pause after reaching the batch size")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-ran
+}
+
+func TestBackpressureBatchNegative(t *testing.T) {
+	SetBackpressureBatch(-1)
+	defer SetBackpressureBatch(0)
+	// Otherwise the conversion wraps around, and senders almost never pause
+	if n := backpressureBatch.Load(); n != 0 {
+		t.Errorf("negative batch size was stored as %d, expected 0", n)
+	}
+}
+
+func BenchmarkBackpressureBatch(b *testing.B) {
+	for _, n := range []int{1, 16} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			SetBackpressureBatch(n)
+			defer SetBackpressureBatch(0)
+			benchmarkFanIn(b, 4)
+		})
+	}
+}
+
+func TestBackpressureThreshold(t *testing.T) {
+	SetBackpressureThreshold(4)
+	defer SetBackpressureThreshold(DefaultBackpressureThreshold)
+	var sender, receiver Inbox
+	release := hold(&receiver)
+	// The running message and 3 more are within the threshold
+	Block(&sender, func() {
+		for idx := 0; idx < 3; idx++ {
+			receiver.Act(&sender, func() {})
+		}
+	})
+	ran := make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatalf("sender paused below the threshold")
+	}
+	Block(&sender, func() {
+		receiver.Act(&sender, func() {})
+	})
+	ran = make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+		t.Errorf("sender didn't pause above the threshold")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-ran
+}
+
+func TestInboxBackpressureThreshold(t *testing.T) {
+	var sender, receiver Inbox
+	receiver.SetBackpressureThreshold(0)
+	release := hold(&receiver)
+	Block(&sender, func() { receiver.Act(&sender, func() {}) })
+	ran := make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+		t.Errorf("sender didn't pause with a threshold of 0")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-ran
+	// Removing the override restores the package default, which a few messages don't reach
+	receiver.SetBackpressureThreshold(-1)
+	release = hold(&receiver)
+	Block(&sender, func() { receiver.Act(&sender, func() {}) })
+	ran = make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Errorf("sender paused below the default threshold")
+	}
+	release()
+}
+
+// BenchmarkInboxBackpressureThreshold has a producer feed a consumer that does trivial work, with various per-Inbox thresholds on the consumer.
+func BenchmarkInboxBackpressureThreshold(b *testing.B) {
+	for _, n := range []int{0, 1, 8, 32, 128} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var producer, consumer Inbox
+			consumer.SetBackpressureThreshold(n)
+			done := make(chan struct{})
+			idx := 0
+			var f func()
+			f = func() {
+				if idx < b.N {
+					idx++
+					consumer.Act(&producer, func() {})
+					producer.Act(nil, f)
+				} else {
+					consumer.Act(&producer, func() { close(done) })
+				}
+			}
+			producer.Act(nil, f)
+			<-done
+		})
+	}
+}
+
+func BenchmarkBackpressureThreshold(b *testing.B) {
+	for _, n := range []int{0, 1, 8, 32, 128} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			SetBackpressureThreshold(n)
+			defer SetBackpressureThreshold(DefaultBackpressureThreshold)
+			benchmarkFanIn(b, 1)
+		})
+	}
+}
+
+// benchmarkFanIn has several Actors send b.N messages, in total, to one receiver.
+func benchmarkFanIn(b *testing.B, senders int) {
+	var receiver Inbox
+	done := make(chan struct{})
+	received := 0
+	count := func() {
+		if received++; received == b.N {
+			close(done)
+		}
+	}
+	inboxes := make([]Inbox, senders)
+	for idx := range inboxes {
+		s := &inboxes[idx]
+		n := b.N / senders
+		if idx < b.N%senders {
+			n++
+		}
+		var f func()
+		f = func() {
+			if n > 0 {
+				n--
+				receiver.Act(s, count)
+				s.Act(nil, f)
+			}
+		}
+		s.Act(nil, f)
+	}
+	<-done
+}
+
+func TestPendingBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	var sender Inbox
+	var receivers [3]Inbox
+	var releases [3]func()
+	for idx := range receivers {
+		releases[idx] = hold(&receivers[idx])
+	}
+	Block(&sender, func() {
+		for idx := range receivers {
+			receivers[idx].Act(&sender, func() {})
+		}
+	})
+	if n := sender.PendingBackpressure(); n != 3 {
+		t.Errorf("pending backpressure %d, expected 3", n)
+	}
+	for idx, release := range releases {
+		release()
+		Block(&receivers[idx], func() {})
+		expected := len(releases) - idx - 1
+		for start := time.Now(); sender.PendingBackpressure() != expected; {
+			if time.Since(start) > time.Second {
+				t.Fatalf("pending backpressure %d, expected %d", sender.PendingBackpressure(), expected)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestBackpressureReleaseHook(t *testing.T) {
+	defer eagerBackpressure()()
+	var sender, receiver Inbox
+	type released struct {
+		sender, receiver Actor
+		waited           time.Duration
+	}
+	releases := make(chan released, 1)
+	SetBackpressureReleaseHook(func(sender, receiver Actor, waited time.Duration) {
+		releases <- released{sender, receiver, waited}
+	})
+	defer SetBackpressureReleaseHook(nil)
+	release := hold(&receiver)
+	receiver.Act(&sender, func() {})
+	time.Sleep(10 * time.Millisecond)
+	release()
+	r := <-releases
+	if r.sender != &sender || r.receiver != &receiver {
+		t.Errorf("hook called with unexpected actors")
+	}
+	if r.waited < 10*time.Millisecond {
+		t.Errorf("waited %v, expected at least 10ms", r.waited)
+	}
+}
+
+func TestBackpressureTimeout(t *testing.T) {
+	defer eagerBackpressure()()
+	SetBackpressureTimeout(10 * time.Millisecond)
+	defer SetBackpressureTimeout(0)
+	var sender, receiver Inbox
+	release := hold(&receiver)
+	timeouts := BackpressureTimeouts()
+	Block(&sender, func() {
+		receiver.Act(&sender, func() {})
+	})
+	ran := make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatalf("sender stayed paused after the timeout")
+	}
+	if n := BackpressureTimeouts() - timeouts; n != 1 {
+		t.Errorf("counted %d timeouts, expected 1", n)
+	}
+	// The late signal must not block the receiver
+	release()
+	Block(&receiver, func() {})
+	if n := sender.PendingBackpressure(); n != 0 {
+		t.Errorf("sender still has %d pending waits", n)
+	}
+}
+
+func TestSelfSendOrdering(t *testing.T) {
+	defer eagerBackpressure()()
+	var a Inbox
+	var others [4]Inbox
+	const count = 10000
+	var expected int
+	var reordered, pressured bool
+	done := make(chan struct{})
+	var step func(n int) func()
+	step = func(n int) func() {
+		return func() {
+			if n != expected {
+				reordered = true
+			}
+			expected++
+			if a.PendingBackpressure() != 0 {
+				pressured = true
+			}
+			if n+1 < count {
+				// a is busy and flooded while it runs this, so sending with itself as the sender would apply backpressure if it weren't exempt
+				a.Act(&a, step(n+1))
+			} else {
+				close(done)
+			}
+		}
+	}
+	// Other Actors keep a's queue long, so self-sends are interleaved with theirs
+	stop := make(chan struct{})
+	for idx := range others {
+		sender := &others[idx]
+		var flood func()
+		flood = func() {
+			select {
+			case <-stop:
+			default:
+				a.Act(sender, func() {})
+				sender.Act(nil, flood)
+			}
+		}
+		sender.Act(nil, flood)
+	}
+	a.Act(nil, step(0))
+	<-done
+	close(stop)
+	Block(&a, func() {
+		if reordered {
+			t.Errorf("self-sent messages ran out of order")
+		}
+		if pressured {
+			t.Errorf("self-sends applied backpressure")
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package phony
+
+// ActBatch is like Act, but sends every action as a single unit, so they run in argument order with no messages from other senders between them.
+// The Inbox's other checks, such as a per-sender limit, the cycle guard, or the DropNewest overflow policy, accept or drop the whole batch, and backpressure is applied at most once, after the last message is queued.
+// Calling ActBatch with no actions does nothing.
+func (a *Inbox) ActBatch(from Actor, actions ...func()) {
+	for _, action := range actions {
+		if action == nil {
+			panic("tried to send nil action")
+		}
+	}
+	if len(actions) == 0 || !a.admit(from, len(actions), false) {
+		return
+	}
+	var first, last *queueElem
+	for _, action := range actions {
+		q := a.prepare(from, action, false, nil)
+		if q == nil {
+			// Every action has the same sender, so if one is part of a cycle then they all are
+			for q := first; q != nil; {
+				next := q.next.Load()
+				*q = queueElem{}
+				elems.Put(q)
+				q = next
+			}
+			return
+		}
+		if last != nil {
+			last.next.Store(q)
+		} else {
+			first = q
+		}
+		last = q
+	}
+	if first != last {
+		first.msg, last.msg = batchFirst(first.msg), batchLast(last.msg)
+	}
+	a.pushBatch(first, last, uint64(len(actions)))
+	a.admitted(from, len(actions))
+	a.pressure(from)
+}
@@ -0,0 +1,77 @@
+package phony
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestActBatch(t *testing.T) {
+	var a Inbox
+	const senders, batches, size = 8, 50, 10
+	type entry struct{ sender, index int }
+	var log []entry
+	var wg sync.WaitGroup
+	wg.Add(senders)
+	for idx := 0; idx < senders; idx++ {
+		sender := idx // Because idx gets mutated in place
+		go func() {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				actions := make([]func(), size)
+				for jdx := range actions {
+					index := jdx // Because jdx gets mutated in place
+					actions[jdx] = func() { log = append(log, entry{sender, index}) }
+				}
+				a.ActBatch(nil, actions...)
+			}
+		}()
+	}
+	wg.Wait()
+	Block(&a, func() {
+		if len(log) != senders*batches*size {
+			t.Fatalf("ran %d messages, expected %d", len(log), senders*batches*size)
+		}
+		for idx := 0; idx < len(log); idx += size {
+			for jdx := 0; jdx < size; jdx++ {
+				if e := log[idx+jdx]; e.sender != log[idx].sender || e.index != jdx {
+					t.Fatalf("batch starting at %d was interleaved or out of order: %v", idx, log[idx:idx+size])
+				}
+			}
+		}
+	})
+}
+
+func TestActBatchEmpty(t *testing.T) {
+	var a Inbox
+	a.ActBatch(nil)
+	if a.Len() != 0 {
+		t.Errorf("empty batch queued %d messages", a.Len())
+	}
+}
+
+func TestActBatchBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	var sender, receiver Inbox
+	release := hold(&receiver)
+	Block(&sender, func() {
+		receiver.ActBatch(&sender, func() {}, func() {}, func() {})
+	})
+	if n := sender.PendingBackpressure(); n != 1 {
+		t.Errorf("batch applied backpressure %d times, expected 1", n)
+	}
+	release()
+	Block(&sender, func() {})
+}
+
+func TestActBatchNil(t *testing.T) {
+	var a Inbox
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a nil action in a batch")
+		}
+		if a.Len() != 0 {
+			t.Errorf("queued %d messages before panicking", a.Len())
+		}
+	}()
+	a.ActBatch(nil, []func(){func() {}, nil}...)
+}
@@ -0,0 +1,204 @@
+package phony
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// BlockDeadline is like Block, but it gives up waiting at the deadline, and returns true only if the action finished in time.
+// If the deadline has already passed, or the Actor has been stopped, then it returns false immediately without sending anything.
+// Giving up doesn't cancel the action, which still runs when the Actor gets to it.
+// It must not be called from an Actor.
+func BlockDeadline(actor Actor, deadline time.Time, action func()) bool {
+	if actor == nil {
+		panic("tried to send to nil actor")
+	} else if action == nil {
+		panic("tried to send nil action")
+	}
+	if blockCheck.Load() {
+		checkBlock(actor)
+	}
+	wait := time.Until(deadline)
+	if wait <= 0 || actor.inbox().stopped.Load() {
+		return false
+	}
+	done := stops.Get().(chan struct{})
+	actor.enqueue(action)
+	actor.enqueue(func() { done <- struct{}{} })
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-done:
+		stops.Put(done)
+		return true
+	case <-timer.C:
+		// The late signal lands in done's buffer, so it must not go back into the pool
+		return false
+	}
+}
+
+// BlockContext is like Block, but it gives up waiting if the context is done first, and returns the context's error.
+// Giving up doesn't cancel the action, which may still run after BlockContext has returned an error.
+// If the context is already done, then it returns the context's error without sending anything, and if the Actor has been stopped, then it returns ErrStopped without running the action, like BlockDeadline returning false.
+// It must not be called from an Actor.
+func BlockContext(ctx context.Context, actor Actor, action func()) error {
+	if actor == nil {
+		panic("tried to send to nil actor")
+	} else if action == nil {
+		panic("tried to send nil action")
+	}
+	if blockCheck.Load() {
+		checkBlock(actor)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if actor.inbox().stopped.Load() {
+		return ErrStopped
+	}
+	done := stops.Get().(chan struct{})
+	actor.enqueue(action)
+	actor.enqueue(func() { done <- struct{}{} })
+	select {
+	case <-done:
+		stops.Put(done)
+		return nil
+	case <-ctx.Done():
+		// The late signal lands in done's buffer, so it must not go back into the pool
+		return ctx.Err()
+	}
+}
+
+// BlockMany is like calling Block for each of the actors, but the messages are all sent before waiting, so the actors run them concurrently and the wait is only as long as the slowest one.
+// BlockAny is the counterpart that waits for the fastest one instead.
+// The action is called by each Actor with that Actor as its argument, and BlockMany returns once every Actor has finished its call.
+// The messages go through the same checks as Act, so actors that drop them, e.g. because they've been stopped, are skipped, and a nil Actor in the slice panics before any messages are sent.
+// It must not be called from an Actor.
+func BlockMany(actors []Actor, action func(Actor)) {
+	for _, actor := range actors {
+		if actor == nil {
+			panic("tried to send to nil actor")
+		}
+	}
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	if blockCheck.Load() {
+		checkBlock(nil)
+	}
+	var wg sync.WaitGroup
+	for _, actor := range actors {
+		a := actor // Because actor gets mutated in place
+		if _, sent := a.inbox().send(nil, func() { action(a) }); !sent {
+			continue
+		}
+		wg.Add(1)
+		a.enqueue(wg.Done)
+	}
+	wg.Wait()
+}
+
+// BlockAny is like BlockMany, but it returns as soon as any one of the actors has finished its call to action, with that Actor's index in actors.
+// This suits a scatter/gather where only the fastest response matters, such as a hedged request.
+// The other calls still run, and may finish after BlockAny has returned, so action must be safe to keep running after the caller has moved on.
+// As with BlockMany, actors that drop the message, e.g. because they've been stopped, are skipped, and if they all are, then BlockAny returns -1 immediately.
+// It must not be called from an Actor.
+func BlockAny(actors []Actor, action func(Actor)) int {
+	for _, actor := range actors {
+		if actor == nil {
+			panic("tried to send to nil actor")
+		}
+	}
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	if blockCheck.Load() {
+		checkBlock(nil)
+	}
+	// The late finishers keep sending after BlockAny returns, so this channel has room for all of them, and it isn't pooled
+	done := make(chan int, len(actors))
+	var sent int
+	for idx, actor := range actors {
+		n, a := idx, actor // Because idx and actor get mutated in place
+		if _, ok := a.inbox().send(nil, func() { action(a) }); !ok {
+			continue
+		}
+		sent++
+		a.enqueue(func() { done <- n })
+	}
+	if sent == 0 {
+		return -1
+	}
+	return <-done
+}
+
+// ErrStopped is returned by functions that send a message and wait for the answer, such as the ones made by AsFunc, if the Actor has been stopped, so the message was never sent.
+var ErrStopped = errors.New("actor stopped")
+
+// ErrDropped is returned by functions that send a message and wait for the answer, such as the ones made by AsFunc, if the Actor's Inbox dropped the message for some other reason, such as its OverflowPolicy.
+var ErrDropped = errors.New("message dropped")
+
+// refused returns the error for a message that the Inbox didn't accept.
+func (a *Inbox) refused() error {
+	if a.stopped.Load() {
+		return ErrStopped
+	}
+	return ErrDropped
+}
+
+// ErrPanicked is returned by functions that wait for a result, such as the ones made by AsFunc, if the action panicked and a panic handler recovered it, so there's no result to return.
+var ErrPanicked = errors.New("action panicked")
+
+// PanicError is the error returned by BlockErr when its action panics.
+type PanicError struct {
+	Value interface{} // the value recovered from the panic
+	Stack []byte      // the stack trace of the panicking goroutine, from debug.Stack
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("action panicked: %v", e.Value)
+}
+
+// Unwrap returns the recovered value if it's an error, so errors.Is and errors.As can see through a PanicError.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// BlockErr is like Block, but if the action panics, the panic is recovered on the Actor's worker, which carries on with the rest of its queue, and returned to the caller as a *PanicError.
+// Without that, a panicking action either crashes the program or, if a panic handler recovers it, lets Block return as though the action had finished normally, with no sign that it failed.
+// It returns nil if the action finished normally, or if the Actor has been stopped, in which case the action isn't run.
+// It must not be called from an Actor.
+func BlockErr(actor Actor, action func()) error {
+	if actor == nil {
+		panic("tried to send to nil actor")
+	} else if action == nil {
+		panic("tried to send nil action")
+	}
+	if blockCheck.Load() {
+		checkBlock(actor)
+	}
+	if actor.inbox().stopped.Load() {
+		return nil
+	}
+	var err error
+	// Run the action and signal in one message, so the signal is sent even if the action panics
+	done := stops.Get().(chan struct{})
+	actor.enqueue(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+			done <- struct{}{}
+		}()
+		action()
+	})
+	<-done
+	stops.Put(done)
+	return err
+}
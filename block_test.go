@@ -0,0 +1,213 @@
+package phony
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBlockDeadline(t *testing.T) {
+	var a Inbox
+	var ran bool
+	if BlockDeadline(&a, time.Now().Add(-time.Second), func() { ran = true }) {
+		t.Errorf("past deadline reported success")
+	}
+	Block(&a, func() {
+		if ran {
+			t.Errorf("action ran after a past deadline")
+		}
+	})
+	if !BlockDeadline(&a, time.Now().Add(time.Second), func() { ran = true }) {
+		t.Errorf("met deadline reported failure")
+	}
+	if !ran {
+		t.Errorf("action didn't run before a met deadline")
+	}
+	release := hold(&a)
+	ran = false
+	if BlockDeadline(&a, time.Now().Add(10*time.Millisecond), func() { ran = true }) {
+		t.Errorf("missed deadline reported success")
+	}
+	release()
+	// The action still runs, and the late signal doesn't block the Actor
+	Block(&a, func() {
+		if !ran {
+			t.Errorf("action didn't run after a missed deadline")
+		}
+	})
+}
+
+func TestBlockContext(t *testing.T) {
+	var a Inbox
+	var ran bool
+	if err := BlockContext(context.Background(), &a, func() { ran = true }); err != nil || !ran {
+		t.Errorf("got error %v and ran %v, expected nil and true", err, ran)
+	}
+	release := hold(&a)
+	ran = false
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := BlockContext(ctx, &a, func() { ran = true }); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	release()
+	// The action still runs, and the abandoned channel is never reused
+	for idx := 0; idx < 16; idx++ {
+		Block(&a, func() {})
+	}
+	Block(&a, func() {
+		if !ran {
+			t.Errorf("action didn't run after the context expired")
+		}
+	})
+	if err := BlockContext(ctx, &a, func() { t.Errorf("ran an action with a done context") }); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBlockContextStopped(t *testing.T) {
+	var a Inbox
+	a.Stop()
+	if err := BlockContext(context.Background(), &a, func() { t.Errorf("ran an action on a stopped actor") }); err != ErrStopped {
+		t.Errorf("got error %v, expected %v", err, ErrStopped)
+	}
+}
+
+func TestBlockContextSlowAction(t *testing.T) {
+	var a Inbox
+	gate := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := BlockContext(ctx, &a, func() { <-gate }); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	close(gate)
+	// If the abandoned channel had gone back into the pool, then the late signal could wake a later Block before its action ran
+	for idx := 0; idx < 1024; idx++ {
+		var ran bool
+		Block(&a, func() { ran = true })
+		if !ran {
+			t.Fatalf("Block returned before its action ran")
+		}
+	}
+}
+
+func TestBlockMany(t *testing.T) {
+	const count = 1000
+	actors := make([]Actor, count)
+	inboxes := make([]Inbox, count)
+	for idx := range actors {
+		actors[idx] = &inboxes[idx]
+	}
+	ran := make([]bool, count)
+	var mutex sync.Mutex
+	var calls int
+	BlockMany(actors, func(actor Actor) {
+		for idx := range actors {
+			if actors[idx] == actor {
+				ran[idx] = true
+			}
+		}
+		mutex.Lock()
+		calls++
+		mutex.Unlock()
+	})
+	if calls != count {
+		t.Errorf("action ran %d times, expected %d", calls, count)
+	}
+	for idx, ok := range ran {
+		if !ok {
+			t.Fatalf("BlockMany returned before actor %d ran", idx)
+		}
+	}
+}
+
+func TestBlockManyNil(t *testing.T) {
+	var a Inbox
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a nil actor")
+		}
+		if a.Len() != 0 {
+			t.Errorf("sent %d messages before panicking", a.Len())
+		}
+	}()
+	BlockMany([]Actor{&a, nil}, func(Actor) {})
+}
+
+func TestBlockErr(t *testing.T) {
+	var a Inbox
+	if err := BlockErr(&a, func() {}); err != nil {
+		t.Errorf("BlockErr returned %v for an action that didn't panic", err)
+	}
+	cause := errors.New("cause")
+	err := BlockErr(&a, func() { panic(cause) })
+	var perr *PanicError
+	if !errors.As(err, &perr) || perr.Value != cause || len(perr.Stack) == 0 {
+		t.Fatalf("BlockErr returned %v, expected a PanicError with the panic value and stack", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("PanicError doesn't unwrap to the panic value")
+	}
+	// The worker survived, and keeps running messages
+	var ran bool
+	Block(&a, func() { ran = true })
+	if !ran {
+		t.Errorf("Actor stopped running messages after a panic")
+	}
+}
+
+func TestBlockAny(t *testing.T) {
+	var slow, fast, stopped Inbox
+	stopped.Stop()
+	release := hold(&slow)
+	actors := []Actor{&stopped, &slow, &fast}
+	var mutex sync.Mutex
+	ran := make(map[Actor]bool)
+	winner := BlockAny(actors, func(actor Actor) {
+		mutex.Lock()
+		ran[actor] = true
+		mutex.Unlock()
+	})
+	if winner != 2 {
+		t.Errorf("winner was %d, expected the fast actor at 2", winner)
+	}
+	release()
+	Block(&slow, func() {})
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !ran[&slow] || ran[&stopped] {
+		t.Errorf("expected the slow actor to finish later and the stopped one to be skipped, got %v", ran)
+	}
+	if n := BlockAny([]Actor{&stopped}, func(Actor) {}); n != -1 {
+		t.Errorf("BlockAny with only stopped actors returned %d, expected -1", n)
+	}
+}
+
+func TestBlockManyDropped(t *testing.T) {
+	var full, idle Inbox
+	full.SetCapacity(1)
+	full.SetOverflowPolicy(DropNewest)
+	release := hold(&full)
+	defer release()
+	var ran []Actor
+	var mutex sync.Mutex
+	// The full Inbox drops its message, so BlockMany doesn't wait for it
+	finishes(t, func() {
+		BlockMany([]Actor{&full, &idle}, func(actor Actor) {
+			mutex.Lock()
+			ran = append(ran, actor)
+			mutex.Unlock()
+		})
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(ran) != 1 || ran[0] != &idle {
+		t.Errorf("expected only the idle actor to run the action")
+	}
+	if n := BlockAny([]Actor{&full}, func(Actor) {}); n != -1 {
+		t.Errorf("BlockAny returned %d when every message was dropped, expected -1", n)
+	}
+}
@@ -0,0 +1,31 @@
+package phony
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var blockCheck atomic.Bool // whether workers register themselves, so Block can check for them
+var workers sync.Map       // maps the goroutine IDs of the registered workers that are running to their Inboxes
+
+// SetBlockCheck enables or disables checking that Block isn't called from an Actor's worker, which risks deadlock if the Actors involved ever Block on each other.
+// While enabled, each worker registers its goroutine ID when it starts, and Block panics, with a message suggesting Act instead, if it's called from a registered worker.
+// An Actor that Blocks on itself is certain to deadlock, rather than just at risk, so that gets its own panic message to make the mistake obvious.
+// Goroutine IDs are parsed from runtime.Stack, which costs on the order of a microsecond for each worker start and Block call, so this is meant for tests and debugging.
+// Only workers started after the check was enabled are registered.
+func SetBlockCheck(enable bool) {
+	blockCheck.Store(enable)
+}
+
+// checkBlock panics if the calling goroutine is a registered worker, with a more specific message if it's target's own worker.
+// The target may be nil if there isn't a single one.
+func checkBlock(target Actor) {
+	inbox, ok := workers.Load(curGoID())
+	if !ok {
+		return
+	}
+	if target != nil && inbox.(*Inbox) == target.inbox() {
+		panic("tried to Block on the Actor whose message is running, which always deadlocks, because the Blocked message waits for the current one to finish")
+	}
+	panic("tried to Block from an Actor's worker, which can deadlock, send the message with Act and have the Actor reply with another Act instead")
+}
@@ -0,0 +1,70 @@
+package phony
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlockCheck(t *testing.T) {
+	SetBlockCheck(true)
+	defer SetBlockCheck(false)
+	var a, b Inbox
+	// Blocking from outside an Actor is fine
+	Block(&b, func() {})
+	recovered := make(chan interface{}, 1)
+	a.Act(nil, func() {
+		defer func() { recovered <- recover() }()
+		Block(&b, func() {})
+	})
+	if r := <-recovered; r == nil {
+		t.Errorf("Block from a worker didn't panic")
+	}
+	SetBlockCheck(false)
+	a.Act(nil, func() {
+		defer func() { recovered <- recover() }()
+		Block(&b, func() {})
+	})
+	if r := <-recovered; r != nil {
+		t.Errorf("Block from a worker panicked with the check disabled: %v", r)
+	}
+}
+
+func TestBlockCheckSelf(t *testing.T) {
+	SetBlockCheck(true)
+	defer SetBlockCheck(false)
+	var a Inbox
+	recovered := make(chan interface{}, 1)
+	a.Act(nil, func() {
+		defer func() { recovered <- recover() }()
+		Block(&a, func() {})
+	})
+	select {
+	case r := <-recovered:
+		if s, _ := r.(string); !strings.Contains(s, "always deadlocks") {
+			t.Errorf("self-Block panicked with the wrong message: %v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("self-Block hung instead of panicking")
+	}
+	// The Actor is still usable, from outside, after the panic was recovered
+	Block(&a, func() {})
+}
+
+func TestBlockCheckSyncMode(t *testing.T) {
+	SetBlockCheck(true)
+	defer SetBlockCheck(false)
+	SetSyncMode(true)
+	defer SetSyncMode(false)
+	var a, b, c Inbox
+	var recovered interface{}
+	a.Act(nil, func() {
+		// b is idle, so its worker runs inline, nested on a's goroutine, and must not unregister a's worker when it finishes
+		b.Act(&a, func() {})
+		defer func() { recovered = recover() }()
+		Block(&c, func() {})
+	})
+	if recovered == nil {
+		t.Errorf("Block from a worker didn't panic after a nested inline run")
+	}
+}
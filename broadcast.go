@@ -0,0 +1,26 @@
+package phony
+
+// Broadcast sends a message to each of the actors, using makeAction to build the message for each one.
+// Backpressure works as if each message was sent with Act, except that it's applied once per receiver, after every message has been sent, so from pauses until each flooded receiver has caught up with everything it was sent.
+// The same Actor may appear more than once in actors, in which case it receives a message for each appearance, but from only waits on it once.
+// Each receiver runs its messages in the order they were sent, but there's no ordering between receivers, so one may run its message before another has even been sent its copy.
+// Nil entries in actors are skipped, so a subscriber list with holes in it can be passed as is.
+// As with Act, from may be nil if backpressure isn't needed.
+func Broadcast(from Actor, actors []Actor, makeAction func(Actor) func()) {
+	var sentTo map[*Inbox]struct{} // only needed for backpressure
+	if from != nil {
+		sentTo = make(map[*Inbox]struct{}, len(actors))
+	}
+	for _, actor := range actors {
+		if actor == nil {
+			continue
+		}
+		a := actor.inbox()
+		if _, sent := a.send(from, makeAction(actor)); sent && sentTo != nil {
+			sentTo[a] = struct{}{}
+		}
+	}
+	for a := range sentTo {
+		a.pressure(from)
+	}
+}
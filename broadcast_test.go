@@ -0,0 +1,58 @@
+package phony
+
+import (
+	"testing"
+)
+
+func TestBroadcast(t *testing.T) {
+	var a, b Inbox
+	var countA, countB int
+	Broadcast(nil, []Actor{&a, nil, &b, &a}, func(actor Actor) func() {
+		if actor == &a {
+			return func() { countA++ }
+		}
+		return func() { countB++ }
+	})
+	Block(&a, func() {
+		if countA != 2 {
+			t.Errorf("duplicate receiver got %d messages, expected 2", countA)
+		}
+	})
+	Block(&b, func() {
+		if countB != 1 {
+			t.Errorf("receiver got %d messages, expected 1", countB)
+		}
+	})
+}
+
+func TestBroadcastEmpty(t *testing.T) {
+	var sender Inbox
+	Block(&sender, func() {
+		Broadcast(&sender, nil, func(Actor) func() {
+			t.Error("built a message for an empty broadcast")
+			return func() {}
+		})
+	})
+	if n := sender.PendingBackpressure(); n != 0 {
+		t.Errorf("empty broadcast applied backpressure %d times", n)
+	}
+}
+
+func TestBroadcastBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	var sender, idle, busy Inbox
+	release := hold(&busy)
+	Block(&sender, func() {
+		Broadcast(&sender, []Actor{&idle, &busy, &busy}, func(Actor) func() { return func() {} })
+	})
+	if n := sender.PendingBackpressure(); n != 1 {
+		t.Errorf("sender has %d backpressure waits, expected 1 for the duplicated busy receiver", n)
+	}
+	ran := make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	release()
+	<-ran
+	if n := sender.PendingBackpressure(); n != 0 {
+		t.Errorf("sender still has %d backpressure waits after the receiver caught up", n)
+	}
+}
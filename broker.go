@@ -0,0 +1,21 @@
+package phony
+
+// TypedBroker distributes published values of type T to subscribed handler functions.
+// It's a convenience wrapper around Topic, for subscribers that don't have an Actor of their own, so each handler is run by its own internal Inbox.
+// Subscription changes are serialized with Publish by the underlying Topic's Inbox, so a concurrent Subscribe and Publish never miss or duplicate a value.
+// The zero value is ready to use.
+type TypedBroker[T any] struct {
+	topic Topic[T]
+}
+
+// Subscribe registers handler to be called with each value published after the subscription is processed, and returns a function that cancels the subscription.
+// Calls to handler are serialized, and happen in the order that the values were published.
+func (b *TypedBroker[T]) Subscribe(handler func(T)) (unsubscribe func()) {
+	return b.topic.Subscribe(new(Inbox), handler).Unsubscribe
+}
+
+// Publish sends a message to the broker, asking it to deliver value to every subscriber.
+// Subscribers which are flooded apply backpressure to the broker, which in turn applies it to publishers.
+func (b *TypedBroker[T]) Publish(from Actor, value T) {
+	b.topic.Publish(from, value)
+}
@@ -0,0 +1,36 @@
+package phony
+
+import "testing"
+
+func TestTypedBroker(t *testing.T) {
+	var b TypedBroker[int]
+	var received []int
+	done := make(chan struct{})
+	subscribed := make(chan struct{})
+	go func() {
+		b.Subscribe(func(n int) {
+			received = append(received, n)
+			if n == 1024 {
+				close(done)
+			}
+		})
+		close(subscribed)
+	}()
+	for idx := 0; idx < 1024; idx++ {
+		b.Publish(nil, idx)
+	}
+	<-subscribed
+	b.Publish(nil, 1024)
+	<-done
+	for idx, n := range received {
+		if n != received[0]+idx {
+			t.Errorf("received %d at index %d after starting at %d", n, idx, received[0])
+		}
+	}
+	unsubscribe := b.Subscribe(func(n int) {
+		t.Errorf("unsubscribed handler received %d", n)
+	})
+	unsubscribe()
+	b.Publish(nil, 0)
+	Block(&b.topic, func() {})
+}
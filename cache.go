@@ -0,0 +1,52 @@
+package phony
+
+// Cache is an Actor that holds loaded values by key, and makes sure that concurrent misses for the same key only load it once.
+// Key state is managed by messages run by the Cache's Inbox, so the single-flight bookkeeping needs no locks.
+type Cache[K comparable, V any] struct {
+	Inbox
+	values  map[K]V
+	loading map[K][]func(V) // callbacks waiting for a load in progress
+}
+
+// NewCache returns a new, empty Cache.
+func NewCache[K comparable, V any]() *Cache[K, V] {
+	return &Cache[K, V]{values: make(map[K]V), loading: make(map[K][]func(V))}
+}
+
+// Get sends a message to the Cache, asking it to call then with the value for key.
+// If the key is missing, and isn't already being loaded, then load is called on a new goroutine, so a slow load doesn't hold up the Cache.
+// Every Get for the key that arrives before the load finishes waits for the same result, and their load functions are ignored.
+// The then function is run by the Cache's Inbox, so it should not block, and would typically send a message to the requesting Actor.
+func (c *Cache[K, V]) Get(from Actor, key K, load func(K) V, then func(V)) {
+	if load == nil || then == nil {
+		panic("tried to send nil action")
+	}
+	c.Act(from, func() {
+		if value, isIn := c.values[key]; isIn {
+			then(value)
+			return
+		}
+		waiters, isLoading := c.loading[key]
+		c.loading[key] = append(waiters, then)
+		if isLoading {
+			return
+		}
+		go func() {
+			value := load(key)
+			c.Act(nil, func() {
+				c.values[key] = value
+				waiters := c.loading[key]
+				delete(c.loading, key)
+				for _, then := range waiters {
+					then(value)
+				}
+			})
+		}()
+	})
+}
+
+// Forget sends a message to the Cache, asking it to drop the value for key, so the next Get loads it again.
+// A load that's already in progress isn't affected.
+func (c *Cache[K, V]) Forget(from Actor, key K) {
+	c.Act(from, func() { delete(c.values, key) })
+}
@@ -0,0 +1,46 @@
+package phony
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	c := NewCache[string, int]()
+	var loads atomic.Int32
+	gate := make(chan struct{})
+	load := func(key string) int {
+		loads.Add(1)
+		<-gate
+		return len(key)
+	}
+	var wg sync.WaitGroup
+	results := make(chan int, 100)
+	for idx := 0; idx < 100; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Get(nil, "hello", load, func(value int) { results <- value })
+		}()
+	}
+	wg.Wait()
+	close(gate)
+	for idx := 0; idx < 100; idx++ {
+		if value := <-results; value != 5 {
+			t.Errorf("got %d, expected 5", value)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loaded %d times, expected 1", n)
+	}
+	// Cached values don't load again, until they're forgotten
+	c.Get(nil, "hello", load, func(value int) { results <- value })
+	<-results
+	c.Forget(nil, "hello")
+	c.Get(nil, "hello", load, func(value int) { results <- value })
+	<-results
+	if n := loads.Load(); n != 2 {
+		t.Errorf("loaded %d times, expected 2", n)
+	}
+}
@@ -0,0 +1,51 @@
+package phony
+
+// ErrorCollector is an Actor that gathers the errors reported by a stream of operations.
+// Errors are appended from messages run by the collector's Inbox, so no locking is needed.
+// The zero value is ready to use and retains an unbounded number of errors.
+type ErrorCollector struct {
+	Inbox
+	errs    []error
+	max     int
+	dropped uint64
+}
+
+// NewErrorCollector returns an ErrorCollector that retains at most max errors between calls to Collect.
+// Errors reported after the limit is reached are counted and then discarded.
+// A max of 0 means that there is no limit.
+func NewErrorCollector(max int) *ErrorCollector {
+	return &ErrorCollector{max: max}
+}
+
+// Report sends a message to the ErrorCollector, asking it to record err.
+// A nil err is ignored.
+func (c *ErrorCollector) Report(from Actor, err error) {
+	if err == nil {
+		return
+	}
+	c.Act(from, func() {
+		if c.max > 0 && len(c.errs) >= c.max {
+			c.dropped++
+			return
+		}
+		c.errs = append(c.errs, err)
+	})
+}
+
+// Collect returns the errors recorded since the last call to Collect, and clears them.
+// It uses Block, so it must not be called from an Actor.
+func (c *ErrorCollector) Collect() []error {
+	var errs []error
+	Block(c, func() {
+		errs, c.errs = c.errs, nil
+	})
+	return errs
+}
+
+// Dropped returns the number of errors discarded because the limit was reached.
+// It uses Block, so it must not be called from an Actor.
+func (c *ErrorCollector) Dropped() uint64 {
+	var n uint64
+	Block(c, func() { n = c.dropped })
+	return n
+}
@@ -0,0 +1,44 @@
+package phony
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCollector(t *testing.T) {
+	var c ErrorCollector
+	var senders [8]Inbox
+	for idx := range senders {
+		s := &senders[idx]
+		Block(s, func() {
+			for n := 0; n < 128; n++ {
+				c.Report(s, fmt.Errorf("sender %d error %d", idx, n))
+				c.Report(s, nil)
+			}
+		})
+	}
+	for idx := range senders {
+		Block(&senders[idx], func() {})
+	}
+	if errs := c.Collect(); len(errs) != len(senders)*128 {
+		t.Errorf("collected %d errors, expected %d", len(errs), len(senders)*128)
+	}
+	if errs := c.Collect(); len(errs) != 0 {
+		t.Errorf("collected %d errors after clearing", len(errs))
+	}
+}
+
+func TestErrorCollectorMax(t *testing.T) {
+	c := NewErrorCollector(10)
+	err := errors.New("test error")
+	for idx := 0; idx < 25; idx++ {
+		c.Report(nil, err)
+	}
+	if errs := c.Collect(); len(errs) != 10 {
+		t.Errorf("collected %d errors, expected 10", len(errs))
+	}
+	if n := c.Dropped(); n != 15 {
+		t.Errorf("dropped %d errors, expected 15", n)
+	}
+}
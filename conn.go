@@ -0,0 +1,76 @@
+package phony
+
+import "errors"
+
+// ErrConnClosed is passed to Use callbacks sent after a ConnManager was closed.
+var ErrConnClosed = errors.New("connection manager closed")
+
+// ConnManager is an Actor that owns a connection of type C, and serializes everything that's done with it.
+// Uses, reconnects, and closing are all messages run by the ConnManager's Inbox, so a Use never overlaps with a Reconnect, and the connection can be swapped without locks.
+type ConnManager[C any] struct {
+	Inbox
+	dial   func() (C, error)
+	close  func(C) error
+	conn   C
+	open   bool // whether conn holds a live connection
+	closed bool
+}
+
+// NewConnManager returns a new ConnManager, which calls dial to open a connection the first time one is needed, and close to close it.
+// Both functions are run by the ConnManager's Inbox, so every other operation waits for them.
+// Errors from close are ignored, since the connection is being discarded anyway.
+func NewConnManager[C any](dial func() (C, error), close func(C) error) *ConnManager[C] {
+	if dial == nil || close == nil {
+		panic("tried to create ConnManager with nil dial or close function")
+	}
+	return &ConnManager[C]{dial: dial, close: close}
+}
+
+// Use sends a message to the ConnManager, asking it to call fn with the connection, opening one first if needed.
+// If a connection couldn't be opened, or the ConnManager was closed, then fn is called with the zero C and the error instead.
+// fn is run by the ConnManager's Inbox, so it has exclusive use of the connection, but it must not keep it after returning.
+func (m *ConnManager[C]) Use(from Actor, fn func(conn C, err error)) {
+	if fn == nil {
+		panic("tried to send nil action")
+	}
+	m.Act(from, func() {
+		var zero C
+		switch {
+		case m.closed:
+			fn(zero, ErrConnClosed)
+		case m.open:
+			fn(m.conn, nil)
+		default:
+			conn, err := m.dial()
+			if err != nil {
+				fn(zero, err)
+				return
+			}
+			m.conn, m.open = conn, true
+			fn(conn, nil)
+		}
+	})
+}
+
+// Reconnect sends a message to the ConnManager, asking it to close the current connection, if any, so the next Use opens a fresh one.
+// The new connection is opened lazily, so a failure to reconnect is reported to the next Use.
+func (m *ConnManager[C]) Reconnect(from Actor) {
+	m.Act(from, m.drop)
+}
+
+// Close sends a message to the ConnManager, asking it to close the current connection, if any, and fail every later Use with ErrConnClosed.
+func (m *ConnManager[C]) Close(from Actor) {
+	m.Act(from, func() {
+		m.drop()
+		m.closed = true
+	})
+}
+
+// drop closes the current connection, if any, it must only be called from the ConnManager's own messages.
+func (m *ConnManager[C]) drop() {
+	if m.open {
+		var zero C
+		m.close(m.conn)
+		m.conn, m.open = zero, false
+	}
+}
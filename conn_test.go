@@ -0,0 +1,68 @@
+package phony
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type testConn struct {
+	id     int
+	closed atomic.Bool
+	using  atomic.Bool
+}
+
+func TestConnManager(t *testing.T) {
+	var dials int
+	m := NewConnManager(func() (*testConn, error) {
+		dials++
+		return &testConn{id: dials}, nil
+	}, func(c *testConn) error {
+		if c.using.Load() {
+			t.Errorf("closed connection %d while it was in use", c.id)
+		}
+		c.closed.Store(true)
+		return nil
+	})
+	var wg sync.WaitGroup
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				m.Use(nil, func(c *testConn, err error) {
+					if err != nil {
+						t.Errorf("unexpected error: %v", err)
+						return
+					}
+					c.using.Store(true)
+					defer c.using.Store(false)
+					if c.closed.Load() {
+						t.Errorf("used connection %d after it was closed", c.id)
+					}
+				})
+				if n%10 == 0 {
+					m.Reconnect(nil)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	m.Close(nil)
+	errs := make(chan error, 1)
+	m.Use(nil, func(c *testConn, err error) { errs <- err })
+	if err := <-errs; err != ErrConnClosed {
+		t.Errorf("got error %v after closing, expected %v", err, ErrConnClosed)
+	}
+}
+
+func TestConnManagerDialError(t *testing.T) {
+	failed := errors.New("dial failed")
+	m := NewConnManager(func() (int, error) { return 0, failed }, func(int) error { return nil })
+	errs := make(chan error, 1)
+	m.Use(nil, func(c int, err error) { errs <- err })
+	if err := <-errs; err != failed {
+		t.Errorf("got error %v, expected %v", err, failed)
+	}
+}
@@ -0,0 +1,71 @@
+package phony
+
+import "context"
+
+// AsFunc adapts an Actor's API to the context-aware function signature that most Go libraries expect.
+// Each call of the returned function sends a message to the Actor, asking it to run fn, and then waits for the result or for the context to be done.
+// If the context is done first, then the returned function returns the context's error, but fn may still run later.
+// If fn panics, and a panic handler recovers it, then the returned function returns ErrPanicked.
+// The message goes through the same checks as Act, so if the Actor has been stopped, the returned function returns ErrStopped without running fn, and if the message is dropped for another reason, such as an OverflowPolicy, it returns ErrDropped.
+// The returned function is safe to call concurrently, since each call is an independent round trip.
+// Like Block, it must not be called from an Actor.
+func AsFunc[T any](to Actor, fn func() (T, error)) func(context.Context) (T, error) {
+	if to == nil {
+		panic("tried to send to nil actor")
+	} else if fn == nil {
+		panic("tried to send nil action")
+	}
+	type result struct {
+		value T
+		err   error
+	}
+	return func(ctx context.Context) (T, error) {
+		var zero T
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		// Buffered, so a late result doesn't block the Actor
+		results := make(chan result, 1)
+		if _, sent := to.inbox().send(nil, func() {
+			r := result{err: ErrPanicked}
+			defer func() { results <- r }()
+			r.value, r.err = fn()
+		}); !sent {
+			return zero, to.inbox().refused()
+		}
+		select {
+		case r := <-results:
+			return r.value, r.err
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// ActCtx is like Act, but the message carries a context, such as one holding a tracing span, which is passed to action when it runs.
+// The context is only carried, and ActCtx doesn't check whether it's done, so an action that cares should check ctx.Err itself.
+// Like the standard library, ActCtx panics if ctx is nil, and ActInherit is the way to pass on the context of the message that's running.
+func (a *Inbox) ActCtx(ctx context.Context, from Actor, action func(context.Context)) {
+	if ctx == nil {
+		panic("tried to send nil context")
+	} else if action == nil {
+		panic("tried to send nil action")
+	}
+	a.Act(from, func() {
+		a.ctx.Store(&ctx)
+		defer a.ctx.Store(nil)
+		action(ctx)
+	})
+}
+
+// ActInherit is like ActCtx, but if from is in the middle of running a message sent with ActCtx or ActInherit, then that message's context is passed on, so a context set by the original caller follows the whole causal chain of messages, without each Actor passing it along by hand.
+// With no context to inherit, action gets context.Background.
+func (a *Inbox) ActInherit(from Actor, action func(context.Context)) {
+	ctx := context.Background()
+	if from != nil {
+		if c := from.inbox().ctx.Load(); c != nil {
+			ctx = *c
+		}
+	}
+	a.ActCtx(ctx, from, action)
+}
@@ -0,0 +1,132 @@
+package phony
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsFunc(t *testing.T) {
+	var a Inbox
+	var count int
+	errOdd := errors.New("odd count")
+	get := AsFunc(&a, func() (int, error) {
+		count++
+		if count%2 == 1 {
+			return count, errOdd
+		}
+		return count, nil
+	})
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var errs int
+	for idx := 0; idx < 64; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := get(context.Background())
+			if (n%2 == 1) != (err != nil) {
+				t.Errorf("unexpected result %d with error %v", n, err)
+			}
+			if err != nil {
+				mutex.Lock()
+				errs++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if errs != 32 {
+		t.Errorf("got %d errors, expected 32", errs)
+	}
+}
+
+func TestAsFuncCancel(t *testing.T) {
+	var a Inbox
+	defer hold(&a)()
+	get := AsFunc(&a, func() (struct{}, error) { return struct{}{}, nil })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := get(ctx); err != context.DeadlineExceeded {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := get(ctx); err != context.DeadlineExceeded {
+		t.Errorf("unexpected error %v with expired context", err)
+	}
+}
+
+func TestActCtx(t *testing.T) {
+	type traceKey struct{}
+	var first, second, third Inbox
+	traces := make(chan interface{}, 3)
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	first.ActCtx(ctx, nil, func(ctx context.Context) {
+		traces <- ctx.Value(traceKey{})
+		second.ActInherit(&first, func(ctx context.Context) {
+			traces <- ctx.Value(traceKey{})
+			third.ActInherit(&second, func(ctx context.Context) {
+				traces <- ctx.Value(traceKey{})
+			})
+		})
+	})
+	for hop := 0; hop < 3; hop++ {
+		if trace := <-traces; trace != "trace-1" {
+			t.Errorf("hop %d saw trace %v, expected trace-1", hop, trace)
+		}
+	}
+	// Once the chain is over, there's nothing left to inherit
+	Block(&third, func() {})
+	first.ActInherit(&third, func(ctx context.Context) {
+		traces <- ctx.Value(traceKey{})
+	})
+	if trace := <-traces; trace != nil {
+		t.Errorf("plain send inherited trace %v", trace)
+	}
+}
+
+func TestActCtxNil(t *testing.T) {
+	var a Inbox
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a nil context")
+		}
+	}()
+	a.ActCtx(nil, nil, func(context.Context) {})
+}
+
+func TestAsFuncPanic(t *testing.T) {
+	var a Inbox
+	a.SetPanicHandler(func(interface{}) {})
+	get := AsFunc(&a, func() (int, error) { panic("test") })
+	finishes(t, func() {
+		if _, err := get(context.Background()); err != ErrPanicked {
+			t.Errorf("got error %v, expected %v", err, ErrPanicked)
+		}
+	})
+}
+
+func TestAsFuncRefused(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(1)
+	a.SetOverflowPolicy(DropNewest)
+	release := hold(&a)
+	var ran bool
+	get := AsFunc(&a, func() (int, error) {
+		ran = true
+		return 1, nil
+	})
+	if _, err := get(context.Background()); err != ErrDropped {
+		t.Errorf("got error %v from a full Inbox, expected %v", err, ErrDropped)
+	}
+	release()
+	a.Stop()
+	if _, err := get(context.Background()); err != ErrStopped {
+		t.Errorf("got error %v from a stopped Inbox, expected %v", err, ErrStopped)
+	}
+	Block(&a, func() {})
+	if ran {
+		t.Errorf("ran a function that was refused")
+	}
+}
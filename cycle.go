@@ -0,0 +1,69 @@
+package phony
+
+import "sync/atomic"
+
+// cycleGuardConfig holds the settings from SetCycleGuard.
+type cycleGuardConfig struct {
+	maxRevisits int
+	onCycle     func([]Actor)
+}
+
+var cycleGuard atomic.Pointer[cycleGuardConfig] // nil means the guard is off
+
+// causalLink is one step in the chain of sends that led to a message.
+type causalLink struct {
+	inbox  *Inbox
+	parent *causalLink
+}
+
+// SetCycleGuard watches for runaway send loops, where a chain of messages, each sent by the handler of the one before, keeps coming back to the same Actors.
+// When a message would visit an Actor that already appears more than maxRevisits times in its chain, the message is dropped and onCycle is called with the chain, oldest first, ending with the Actor it was sent to.
+// Chains are only followed through sends that name the running Actor as the sender, so sends from non-Actor code, or with a nil sender, start a new chain.
+// The Actors in the chain are the Inboxes involved, rather than the structs they're embedded in.
+// onCycle runs on the sender's worker, so it must be fast and non-blocking.
+// This costs an allocation and a walk of the chain for each send, so it's meant for debugging, and passing a nil onCycle turns it off.
+// Note that an Actor which legitimately loops by sending to itself, with itself as the sender, will also trip the guard.
+func SetCycleGuard(maxRevisits int, onCycle func(chain []Actor)) {
+	if onCycle == nil {
+		cycleGuard.Store(nil)
+		return
+	}
+	cycleGuard.Store(&cycleGuardConfig{maxRevisits: maxRevisits, onCycle: onCycle})
+}
+
+// guardCycle extends the sender's causal chain with the Inbox, and returns action wrapped so the chain is visible while it runs.
+// It returns nil if the message should be dropped because the chain revisits the Inbox too often.
+func (a *Inbox) guardCycle(from Actor, action func()) func() {
+	guard := cycleGuard.Load()
+	if guard == nil {
+		return action
+	}
+	sender := from.inbox()
+	parent := sender.cause.Load()
+	if parent == nil {
+		// The sender's message wasn't part of a chain, so start one with it
+		parent = &causalLink{inbox: sender}
+	}
+	link := &causalLink{inbox: a, parent: parent}
+	visits, length := 0, 0
+	for l := link.parent; l != nil; l = l.parent {
+		if l.inbox == a {
+			visits++
+		}
+		length++
+	}
+	if visits > guard.maxRevisits {
+		chain := make([]Actor, length+1)
+		for l := link; l != nil; l = l.parent {
+			chain[length] = l.inbox
+			length--
+		}
+		guard.onCycle(chain)
+		return nil
+	}
+	return func() {
+		a.cause.Store(link)
+		defer a.cause.Store(nil)
+		action()
+	}
+}
@@ -0,0 +1,56 @@
+package phony
+
+import "testing"
+
+func TestCycleGuard(t *testing.T) {
+	cycles := make(chan []Actor, 1)
+	SetCycleGuard(2, func(chain []Actor) { cycles <- chain })
+	defer SetCycleGuard(0, nil)
+	var a, b Inbox
+	var hops int
+	var ping, pong func()
+	ping = func() {
+		hops++
+		b.Act(&a, pong)
+	}
+	pong = func() {
+		hops++
+		a.Act(&b, ping)
+	}
+	a.Act(nil, ping)
+	chain := <-cycles
+	// a, b, a, b, a, b, and then the dropped send to a
+	if len(chain) != 7 {
+		t.Fatalf("got a chain of %d actors, expected 7", len(chain))
+	}
+	for idx, actor := range chain {
+		expected := &a
+		if idx%2 == 1 {
+			expected = &b
+		}
+		if actor != expected {
+			t.Errorf("actor %d in the chain was wrong", idx)
+		}
+	}
+	Block(&a, func() {})
+	Block(&b, func() {})
+	if hops != 6 {
+		t.Errorf("ran %d hops, expected 6", hops)
+	}
+}
+
+func TestCycleGuardPanic(t *testing.T) {
+	SetCycleGuard(2, func([]Actor) {})
+	defer SetCycleGuard(0, nil)
+	var a, b Inbox
+	a.SetPanicHandler(func(interface{}) {})
+	Block(&b, func() {
+		a.Act(&b, func() { panic("test") })
+	})
+	var cause *causalLink
+	Block(&a, func() { cause = a.cause.Load() })
+	// Otherwise the next message a sends would extend a chain it isn't part of
+	if cause != nil {
+		t.Error("a panicking message left its causal chain behind")
+	}
+}
@@ -0,0 +1,55 @@
+package phony
+
+import "time"
+
+// Debouncer is an Actor that coalesces bursts of signals into a single call to fire, made once a key has been quiet for the configured delay.
+// Each key is debounced independently, and all per-key state is managed by messages run by the Debouncer's Inbox.
+type Debouncer struct {
+	Inbox
+	delay  time.Duration
+	fire   func(key any)
+	timers map[any]*debounceTimer
+	after  func(time.Duration, func()) func() bool // Starts a timer and returns its stop function, replaced in tests
+}
+
+// A pending call to fire, identified by pointer so stale timers can be ignored
+type debounceTimer struct {
+	stop func() bool
+}
+
+// NewDebouncer returns a Debouncer that calls fire for a key once delay has passed since the key was last signaled.
+// The fire function is run by the Debouncer's Inbox, so it should not block, and may e.g. send a message to another Actor.
+func NewDebouncer(delay time.Duration, fire func(key any)) *Debouncer {
+	if fire == nil {
+		panic("tried to create Debouncer with nil fire function")
+	}
+	return &Debouncer{
+		delay:  delay,
+		fire:   fire,
+		timers: make(map[any]*debounceTimer),
+		after: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+	}
+}
+
+// Signal sends a message to the Debouncer, restarting the quiet period for key.
+// The key must be comparable, since it's used as a map key.
+func (d *Debouncer) Signal(from Actor, key any) {
+	d.Act(from, func() {
+		if old, isIn := d.timers[key]; isIn {
+			old.stop()
+		}
+		timer := new(debounceTimer)
+		d.timers[key] = timer
+		timer.stop = d.after(d.delay, func() {
+			d.Act(nil, func() {
+				// The timer may have fired after being replaced but before being stopped
+				if d.timers[key] == timer {
+					delete(d.timers, key)
+					d.fire(key)
+				}
+			})
+		})
+	})
+}
@@ -0,0 +1,111 @@
+package phony
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced replacement for time.AfterFunc
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Duration
+	f       func()
+	stopped bool
+}
+
+func (c *fakeClock) afterFunc(d time.Duration, f func()) func() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	timer := &fakeTimer{at: c.now + d, f: f}
+	c.timers = append(c.timers, timer)
+	return func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		wasPending := !timer.stopped
+		timer.stopped = true
+		return wasPending
+	}
+}
+
+// elapse moves the clock forward and runs any timers that expire, in the order they were started
+func (c *fakeClock) elapse(d time.Duration) {
+	c.mutex.Lock()
+	c.now += d
+	var due []func()
+	var pending []*fakeTimer
+	for _, timer := range c.timers {
+		switch {
+		case timer.stopped:
+		case timer.at <= c.now:
+			timer.stopped = true
+			due = append(due, timer.f)
+		default:
+			pending = append(pending, timer)
+		}
+	}
+	c.timers = pending
+	c.mutex.Unlock()
+	for _, f := range due {
+		f()
+	}
+}
+
+// time returns the clock's current time, as an offset from the zero time.Time
+func (c *fakeClock) time() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return time.Time{}.Add(c.now)
+}
+
+func TestDebouncer(t *testing.T) {
+	var clock fakeClock
+	var fired []string
+	d := NewDebouncer(10*time.Millisecond, func(key any) {
+		fired = append(fired, key.(string))
+	})
+	d.after = clock.afterFunc
+	step := func(dt time.Duration, keys ...string) {
+		for _, key := range keys {
+			d.Signal(nil, key)
+		}
+		Block(d, func() {})
+		clock.elapse(dt)
+		Block(d, func() {})
+	}
+	step(4*time.Millisecond, "a", "b", "a")
+	step(4*time.Millisecond, "a")
+	step(4*time.Millisecond, "b", "a")
+	step(4*time.Millisecond, "b")
+	if len(fired) != 0 {
+		t.Errorf("fired %v during bursts", fired)
+	}
+	step(4 * time.Millisecond)
+	if len(fired) != 1 || fired[0] != "a" {
+		t.Errorf("fired %v after a settled", fired)
+	}
+	step(2 * time.Millisecond)
+	if len(fired) != 2 || fired[1] != "b" {
+		t.Errorf("fired %v after b settled", fired)
+	}
+	step(100 * time.Millisecond)
+	if len(fired) != 2 {
+		t.Errorf("fired %v after both settled", fired)
+	}
+}
+
+func TestDebouncerTimer(t *testing.T) {
+	done := make(chan any, 1)
+	d := NewDebouncer(time.Millisecond, func(key any) { done <- key })
+	for idx := 0; idx < 10; idx++ {
+		d.Signal(nil, 1)
+	}
+	if key := <-done; key != 1 {
+		t.Errorf("fired with unexpected key %v", key)
+	}
+}
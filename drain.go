@@ -0,0 +1,185 @@
+package phony
+
+import (
+	"reflect"
+	"runtime"
+	"time"
+)
+
+// DrainReverse stops the Inbox, runs the messages that are still queued in reverse order (newest first), and then runs done.
+// This is meant for LIFO cleanup, such as unwinding a stack of operations when an Actor shuts down.
+// Messages that the Inbox queues for itself, such as the ones a pending Block or backpressure uses, stay where they are, so only the runs of ordinary messages between them are reversed, and Block still waits for its action.
+// The messages from one call to ActBatch are reversed as a unit, so they still run in order, with nothing between them.
+// The message that's currently running, if any, finishes normally, and done runs on the Inbox's worker after the reversed messages.
+// DrainReverse panics if a previous reverse drain is still pending.
+func (a *Inbox) DrainReverse(done func()) {
+	if done == nil {
+		panic("tried to send nil action")
+	}
+	a.Stop()
+	q := elems.Get().(*queueElem)
+	*q = queueElem{msg: done}
+	// The marker must be visible before it's queued, so the worker can't pass it unnoticed
+	if !a.reverse.CompareAndSwap(nil, q) {
+		panic("tried to start a second reverse drain")
+	}
+	a.push(q)
+}
+
+// pinned wraps a message that the Inbox queues for itself, so a reverse drain can tell it apart from ordinary messages, and leave it where it is.
+// Messages are told apart by the code pointer of the wrapper, so it must never be inlined, which would give the closure a different one.
+//
+//go:noinline
+func pinned(msg func()) func() {
+	return func() { msg() }
+}
+
+// batchFirst wraps the first message of a batch, so a reverse drain can keep the batch together.
+//
+//go:noinline
+func batchFirst(msg func()) func() {
+	return func() { msg() }
+}
+
+// batchLast wraps the last message of a batch, so a reverse drain can keep the batch together.
+//
+//go:noinline
+func batchLast(msg func()) func() {
+	return func() { msg() }
+}
+
+var (
+	pinnedCode     = reflect.ValueOf(pinned(nop)).Pointer()
+	batchFirstCode = reflect.ValueOf(batchFirst(nop)).Pointer()
+	batchLastCode  = reflect.ValueOf(batchLast(nop)).Pointer()
+)
+
+// code returns the code pointer of q's message, which identifies the wrappers above.
+func code(q *queueElem) uintptr {
+	return reflect.ValueOf(q.msg).Pointer()
+}
+
+// reverseUntil reverses the order of the messages queued between head and marker, it's called by the worker when it sees a pending DrainReverse.
+// Pinned messages stay where they are, and each run of ordinary messages between them is reversed, with batches kept in order.
+// Every message before the marker is behind the tail, so once its next pointer is set, nothing but the worker will touch it again.
+func (a *Inbox) reverseUntil(head, marker *queueElem) {
+	var queued []*queueElem
+	for q := loadNext(head); q != marker; q = loadNext(q) {
+		queued = append(queued, q)
+	}
+	order := make([]*queueElem, 0, len(queued))
+	for start := 0; start < len(queued); {
+		if code(queued[start]) == pinnedCode {
+			order = append(order, queued[start])
+			start++
+			continue
+		}
+		end := start
+		for end < len(queued) && code(queued[end]) != pinnedCode {
+			end++
+		}
+		// Take the run from the back, one message, or one whole batch, at a time
+		for last := end; last > start; {
+			first := last - 1
+			if code(queued[first]) == batchLastCode {
+				for first > start && code(queued[first]) != batchFirstCode {
+					first--
+				}
+			}
+			order = append(order, queued[first:last]...)
+			last = first
+		}
+		start = end
+	}
+	prev := head
+	for _, q := range order {
+		prev.next.Store(q)
+		prev = q
+	}
+	prev.next.Store(marker)
+}
+
+// loadNext returns q's next pointer, busy looping until it's been set, for use only when another message is known to follow q.
+func loadNext(q *queueElem) *queueElem {
+	next := q.next.Load()
+	for next == nil {
+		// Gosched to avoid blocking the thread in the mean time
+		runtime.Gosched()
+		next = q.next.Load()
+	}
+	return next
+}
+
+// Drain stops an Actor, and then waits for the messages that were already queued to finish running.
+// Once it returns, later calls to Act are no-ops, so any resources the Actor's messages were using can be reclaimed.
+// An action set with SetOnStop may still be running, or about to run, when Drain returns.
+// It blocks, so it must not be called from an Actor.
+func Drain(actor Actor) {
+	if actor == nil {
+		panic("tried to send to nil actor")
+	}
+	a := actor.inbox()
+	a.Stop()
+	done := stops.Get().(chan struct{})
+	a.enqueue(func() { done <- struct{}{} })
+	<-done
+	stops.Put(done)
+}
+
+// DrainWithProgress stops an Actor and then waits for the messages that are still queued to finish, calling onProgress with the number remaining once per interval, and with 0 once they're all done.
+// The reported counts never increase, even if internal messages, such as those used to apply backpressure, are added while draining.
+// onProgress runs on the calling goroutine, which is blocked until the drain is over, so DrainWithProgress must not be called from an Actor.
+func DrainWithProgress(actor Actor, onProgress func(remaining int), interval time.Duration) {
+	if actor == nil {
+		panic("tried to send to nil actor")
+	} else if onProgress == nil {
+		panic("tried to send nil action")
+	}
+	a := actor.inbox()
+	a.Stop()
+	done := make(chan struct{})
+	a.enqueue(func() { close(done) })
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := -1
+	for {
+		select {
+		case <-done:
+			onProgress(0)
+			return
+		case <-ticker.C:
+			// Don't count the message that closes done
+			remaining := a.Len() - 1
+			if remaining < 0 {
+				remaining = 0
+			}
+			if last >= 0 && remaining > last {
+				remaining = last
+			}
+			last = remaining
+			onProgress(remaining)
+		}
+	}
+}
+
+// WaitIdle blocks until the Inbox has been seen with an empty queue and no running worker, which means everything sent to it before then has finished.
+// Unlike Block with an empty action, which only waits for the messages ahead of it, WaitIdle keeps waiting while other senders keep the Inbox busy, so it may never return if they don't stop.
+// The Inbox can be sent more work as soon as WaitIdle has seen it idle, so the result only means anything once every sender has stopped, e.g. during a graceful shutdown, or at the end of a test.
+// It blocks, so it must not be called from an Actor.
+func (a *Inbox) WaitIdle() {
+	if blockCheck.Load() {
+		checkBlock(a)
+	}
+	for a.tail.Load() != nil {
+		if a.Len() > 0 {
+			// Wait for everything that's queued so far, then check again
+			done := stops.Get().(chan struct{})
+			a.enqueue(func() { done <- struct{}{} })
+			<-done
+			stops.Put(done)
+			continue
+		}
+		// The last message has finished, and the worker is about to stop, unless there's a race with a new message
+		runtime.Gosched()
+	}
+}
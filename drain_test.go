@@ -0,0 +1,169 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrainReverse(t *testing.T) {
+	var a Inbox
+	var results []int
+	release := hold(&a)
+	for idx := 0; idx < 1024; idx++ {
+		n := idx // Because idx gets mutated in place
+		a.Act(nil, func() { results = append(results, n) })
+	}
+	done := make(chan struct{})
+	a.DrainReverse(func() { close(done) })
+	a.Act(nil, func() { results = append(results, -1) })
+	release()
+	<-done
+	if len(results) != 1024 {
+		t.Fatalf("ran %d messages, expected 1024", len(results))
+	}
+	for idx, n := range results {
+		if n != 1023-idx {
+			t.Errorf("value %d at index %d", n, idx)
+		}
+	}
+}
+
+func TestDrainReverseBlock(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	var results []int
+	a.Act(nil, func() { results = append(results, 0) })
+	blocked := make(chan bool)
+	go func() {
+		var ran bool
+		Block(&a, func() { ran = true })
+		blocked <- ran
+	}()
+	// The held message, the earlier Act, and the Block's action and signal
+	for start := time.Now(); a.Len() < 4; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("Block's messages were never queued")
+		}
+	}
+	a.ActBatch(nil, func() { results = append(results, 1) }, func() { results = append(results, 2) })
+	a.Act(nil, func() { results = append(results, 3) })
+	done := make(chan struct{})
+	a.DrainReverse(func() { close(done) })
+	release()
+	if !<-blocked {
+		t.Errorf("Block returned before its action ran")
+	}
+	<-done
+	// Block's messages split the queue, and only the messages after them are reversed, with the batch kept in order
+	expected := []int{0, 3, 1, 2}
+	if len(results) != len(expected) {
+		t.Fatalf("got %v, expected %v", results, expected)
+	}
+	for idx, n := range expected {
+		if results[idx] != n {
+			t.Fatalf("got %v, expected %v", results, expected)
+		}
+	}
+}
+
+func TestDrainReverseIdle(t *testing.T) {
+	var a Inbox
+	Block(&a, func() {})
+	done := make(chan struct{})
+	a.DrainReverse(func() { close(done) })
+	<-done
+}
+
+func TestDrainWithProgress(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	for idx := 0; idx < 64; idx++ {
+		a.Act(nil, func() { time.Sleep(time.Millisecond) })
+	}
+	var reports []int
+	DrainWithProgress(&a, func(remaining int) {
+		if len(reports) == 0 {
+			// Let the drain start once the blocked count was seen
+			release()
+		}
+		reports = append(reports, remaining)
+	}, 2*time.Millisecond)
+	if len(reports) < 2 {
+		t.Fatalf("got %d progress reports, expected more", len(reports))
+	}
+	if reports[0] < 64 {
+		t.Errorf("first report was %d, expected at least 64 while blocked", reports[0])
+	}
+	for idx := 1; idx < len(reports); idx++ {
+		if reports[idx] > reports[idx-1] {
+			t.Errorf("remaining count increased from %d to %d", reports[idx-1], reports[idx])
+		}
+	}
+	if last := reports[len(reports)-1]; last != 0 {
+		t.Errorf("last report was %d, expected 0", last)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	var a Inbox
+	var results []int
+	release := hold(&a)
+	for idx := 0; idx < 64; idx++ {
+		n := idx // Because idx gets mutated in place
+		a.Act(nil, func() { results = append(results, n) })
+	}
+	time.AfterFunc(time.Millisecond, release)
+	Drain(&a)
+	if len(results) != 64 {
+		t.Errorf("ran %d messages before Drain returned, expected 64", len(results))
+	}
+	a.Act(nil, func() { results = append(results, -1) })
+	Drain(&a)
+	if len(results) != 64 {
+		t.Errorf("ran a message sent after Drain")
+	}
+}
+
+func TestWaitIdle(t *testing.T) {
+	var a Inbox
+	a.WaitIdle() // Never used, so already idle
+	// Each message queues another, so a Block issued early returns long before the chain ends
+	const count = 1000
+	var done int
+	var step func()
+	step = func() {
+		if done++; done < count {
+			a.Act(nil, step)
+		}
+	}
+	a.Act(nil, step)
+	a.WaitIdle()
+	if a.tail.Load() != nil || a.busy.Load() {
+		t.Errorf("WaitIdle returned while the Inbox was still running")
+	}
+	Block(&a, func() {
+		if done != count {
+			t.Errorf("WaitIdle returned after %d of %d messages", done, count)
+		}
+	})
+}
+
+func TestWaitIdleBacklog(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	var ran int
+	for idx := 0; idx < 1000; idx++ {
+		a.Act(nil, func() { ran++ })
+	}
+	if !a.Busy() {
+		t.Errorf("Inbox with a backlog isn't busy")
+	}
+	release()
+	a.WaitIdle()
+	if a.Busy() {
+		t.Errorf("Inbox is still busy after WaitIdle")
+	}
+	if ran != 1000 {
+		t.Errorf("WaitIdle returned after %d of 1000 queued messages", ran)
+	}
+}
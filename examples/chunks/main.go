@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Arceliar/phony"
+)
+
+// A summer adds up a long list of numbers, one chunk at a time.
+type summer struct {
+	phony.Inbox
+	token *phony.Token
+	total int
+}
+
+// Sum asks the summer to start adding numbers, and to send the result to done.
+// The work is done in chunks, and each chunk sends the next one as a new message, so other messages can run in between.
+func (s *summer) Sum(from phony.Actor, nums []int, done func(total int, cancelled bool)) {
+	s.Act(from, func() {
+		s.token, s.total = new(phony.Token), 0
+		s.sumChunk(s.token, nums, done)
+	})
+}
+
+func (s *summer) sumChunk(token *phony.Token, nums []int, done func(int, bool)) {
+	if token.Cancelled() {
+		done(s.total, true)
+		return
+	}
+	chunk := nums
+	if len(chunk) > 1000 {
+		chunk = chunk[:1000]
+	}
+	for _, n := range chunk {
+		s.total += n
+	}
+	if len(chunk) == len(nums) {
+		done(s.total, false)
+		return
+	}
+	s.Act(nil, func() { s.sumChunk(token, nums[len(chunk):], done) })
+}
+
+// Cancel asks the summer to stop working on the current sum.
+// It's just another message, so it runs between two chunks.
+func (s *summer) Cancel(from phony.Actor) {
+	s.Act(from, func() {
+		if s.token != nil {
+			s.token.Cancel()
+		}
+	})
+}
+
+func main() {
+	nums := make([]int, 1000000)
+	for idx := range nums {
+		nums[idx] = idx
+	}
+	s := new(summer)
+	results := make(chan string, 1)
+	s.Sum(nil, nums, func(total int, cancelled bool) {
+		results <- fmt.Sprint("total: ", total, ", cancelled: ", cancelled)
+	})
+	s.Cancel(nil) // This runs after the first chunk, so the sum is cancelled early
+	fmt.Println(<-results)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Arceliar/phony"
+)
+
+// Requests are plain types, and the Facade routes each one by its type.
+type put struct {
+	key, value string
+}
+
+type get struct {
+	key   string
+	reply func(string)
+}
+
+type logLine string
+
+// store is an internal Actor that owns a map.
+type store struct {
+	phony.Inbox
+	data map[string]string
+}
+
+// logger is an internal Actor that owns a log.
+type logger struct {
+	phony.Inbox
+	lines []string
+}
+
+// newService builds the subsystem, and only the Facade needs to be handed out to callers.
+func newService() (*phony.Facade, *logger) {
+	s := &store{data: make(map[string]string)}
+	l := new(logger)
+	f := new(phony.Facade)
+	phony.Route(f, s, func(p put) {
+		s.data[p.key] = p.value
+	})
+	phony.Route(f, s, func(g get) {
+		g.reply(s.data[g.key])
+	})
+	phony.Route(f, l, func(line logLine) {
+		l.lines = append(l.lines, string(line))
+	})
+	return f, l
+}
+
+func main() {
+	service, l := newService()
+	service.Send(nil, logLine("starting"))
+	service.Send(nil, put{"hello", "world"})
+	// Callers don't know which internal Actor answers, they just wait for the reply
+	result := make(chan string, 1)
+	service.Send(nil, get{"hello", func(value string) { result <- value }})
+	fmt.Println("hello:", <-result)
+	service.Send(nil, logLine("done"))
+	phony.Block(service, func() {}) // Wait for the Facade to forward everything
+	phony.Block(l, func() { fmt.Println("log:", l.lines) })
+}
@@ -0,0 +1,60 @@
+package phony
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Facade is an Actor that presents a subsystem of internal Actors as a single one, routing each request to the internal Actor that handles its type.
+// Requests are forwarded with the Facade as the sender, so a busy internal Actor applies backpressure to the Facade, which passes it on to the callers.
+// The zero value is a Facade with no routes.
+type Facade struct {
+	Inbox
+	routes atomic.Pointer[[]facadeRoute] // copied on write, so Send can read it without a message
+}
+
+// facadeRoute returns a function that forwards request to the route's Actor, or nil if the request doesn't match.
+type facadeRoute func(request any) func()
+
+// Route registers handler to run on the Inbox of to, for each request sent to the Facade whose dynamic type is R, or implements R if it's an interface type.
+// Routes are tried in the order they were registered, and the first match wins.
+// Registration takes effect immediately, so it applies to every request sent afterwards.
+func Route[R any](f *Facade, to Actor, handler func(R)) {
+	if to == nil {
+		panic("tried to send to nil actor")
+	} else if handler == nil {
+		panic("tried to send nil action")
+	}
+	route := func(request any) func() {
+		r, ok := request.(R)
+		if !ok {
+			return nil
+		}
+		return func() { to.Act(f, func() { handler(r) }) }
+	}
+	for {
+		old := f.routes.Load()
+		var routes []facadeRoute
+		if old != nil {
+			routes = append(routes, *old...)
+		}
+		routes = append(routes, route)
+		if f.routes.CompareAndSwap(old, &routes) {
+			return
+		}
+	}
+}
+
+// Send sends request to the Facade, which forwards it to the internal Actor registered for its type.
+// Sending a request with no matching route is a programming error, so Send panics, on the caller's goroutine, without sending anything.
+func (f *Facade) Send(from Actor, request any) {
+	if routes := f.routes.Load(); routes != nil {
+		for _, route := range *routes {
+			if forward := route(request); forward != nil {
+				f.Act(from, forward)
+				return
+			}
+		}
+	}
+	panic(fmt.Sprintf("no route for request of type %T", request))
+}
@@ -0,0 +1,68 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFacade(t *testing.T) {
+	type deposit int
+	type withdraw int
+	var f Facade
+	var deposits, withdrawals Inbox
+	var deposited, withdrawn int
+	Route(&f, &deposits, func(d deposit) { deposited += int(d) })
+	Route(&f, &withdrawals, func(w withdraw) { withdrawn += int(w) })
+	for idx := 0; idx < 10; idx++ {
+		f.Send(nil, deposit(idx))
+		f.Send(nil, withdraw(1))
+	}
+	Block(&f, func() {})
+	Block(&deposits, func() {})
+	Block(&withdrawals, func() {})
+	if deposited != 45 || withdrawn != 10 {
+		t.Errorf("deposited %d and withdrew %d, expected 45 and 10", deposited, withdrawn)
+	}
+}
+
+func TestFacadeBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	var f Facade
+	var worker, sender Inbox
+	release := hold(&worker)
+	Route(&f, &worker, func(int) {})
+	Block(&f, func() {})
+	// The Facade forwards to the busy worker, so it should pause
+	sent := make(chan struct{})
+	sender.Act(nil, func() {
+		f.Send(&sender, 1)
+		close(sent)
+	})
+	<-sent
+	for deadline := time.Now().Add(time.Second); f.PendingBackpressure() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("facade never applied backpressure")
+		}
+	}
+	ran := make(chan struct{})
+	f.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+		t.Errorf("facade didn't pause while the internal worker was busy")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-ran
+}
+
+func TestFacadeNoRoute(t *testing.T) {
+	var f Facade
+	var worker Inbox
+	Route(&f, &worker, func(int) {})
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a request with no route")
+		}
+	}()
+	f.Send(nil, "unrouted")
+}
@@ -0,0 +1,88 @@
+package phony
+
+import "sync"
+
+// Future holds a value of type T that will be set at some point, typically by an Actor answering a Request.
+// Non-Actor code can wait for the value with Get, while Actors should use OnReady, which delivers the value as a message instead of blocking.
+type Future[T any] struct {
+	mutex     sync.Mutex
+	owner     Actor
+	ready     chan struct{}
+	value     T
+	set       bool
+	callbacks []func(T)
+}
+
+// NewFuture returns a new Future, whose OnReady callbacks are sent to owner as messages.
+// If owner is nil, then callbacks run on whichever goroutine sets the value instead.
+func NewFuture[T any](owner Actor) *Future[T] {
+	return &Future[T]{owner: owner, ready: make(chan struct{})}
+}
+
+// Request sends a message from one Actor to another, asking it to run fn, and returns a Future that's set to the result.
+// The message is sent with from as the sender, so a flooded to applies backpressure to from, and from is the Future's owner, so OnReady callbacks run on from.
+// If fn panics, and a panic handler recovers it, then the Future is set to the zero T, so nothing waits on it forever.
+func Request[T any](from Actor, to Actor, fn func() T) *Future[T] {
+	if to == nil {
+		panic("tried to send to nil actor")
+	} else if fn == nil {
+		panic("tried to send nil action")
+	}
+	f := NewFuture[T](from)
+	to.Act(from, func() {
+		var value T
+		defer func() { f.Set(value) }()
+		value = fn()
+	})
+	return f
+}
+
+// Set sets the Future's value, waking anything waiting in Get and delivering it to any OnReady callbacks.
+// Only the first call to Set has any effect, later values are ignored.
+func (f *Future[T]) Set(value T) {
+	f.mutex.Lock()
+	if f.set {
+		f.mutex.Unlock()
+		return
+	}
+	f.value, f.set = value, true
+	callbacks := f.callbacks
+	f.callbacks = nil
+	close(f.ready)
+	f.mutex.Unlock()
+	for _, callback := range callbacks {
+		f.deliver(callback)
+	}
+}
+
+// Get waits for the Future's value to be set, and returns it.
+// It blocks, so it must not be called from an Actor.
+func (f *Future[T]) Get() T {
+	<-f.ready
+	return f.value
+}
+
+// OnReady arranges for callback to be called with the Future's value once it's set, as a message to the Future's owner.
+// If the value is already set, then the message is sent immediately.
+func (f *Future[T]) OnReady(callback func(T)) {
+	if callback == nil {
+		panic("tried to send nil action")
+	}
+	f.mutex.Lock()
+	if !f.set {
+		f.callbacks = append(f.callbacks, callback)
+		f.mutex.Unlock()
+		return
+	}
+	f.mutex.Unlock()
+	f.deliver(callback)
+}
+
+// deliver calls callback with the value, on the owner if there is one.
+func (f *Future[T]) deliver(callback func(T)) {
+	if f.owner == nil {
+		callback(f.value)
+		return
+	}
+	f.owner.Act(nil, func() { callback(f.value) })
+}
@@ -0,0 +1,40 @@
+package phony
+
+import "testing"
+
+func TestRequest(t *testing.T) {
+	var requester, responder Inbox
+	var count int
+	f := Request(&requester, &responder, func() int {
+		count++
+		return count * 10
+	})
+	if value := f.Get(); value != 10 {
+		t.Errorf("got %d, expected 10", value)
+	}
+	got := make(chan int, 2)
+	// Callbacks run on the requester, whether they're added before or after the value is set
+	Block(&requester, func() {
+		f.OnReady(func(value int) { got <- value })
+		g := Request(&requester, &responder, func() int { return 20 })
+		g.OnReady(func(value int) { got <- value })
+	})
+	if a, b := <-got, <-got; a+b != 30 {
+		t.Errorf("got %d and %d, expected 10 and 20", a, b)
+	}
+	f.Set(99)
+	if value := f.Get(); value != 10 {
+		t.Errorf("second Set changed the value to %d", value)
+	}
+}
+
+func TestRequestPanic(t *testing.T) {
+	var a Inbox
+	a.SetPanicHandler(func(interface{}) {})
+	f := Request(nil, &a, func() int { panic("test") })
+	finishes(t, func() {
+		if n := f.Get(); n != 0 {
+			t.Errorf("got %d from a panicking action, expected 0", n)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package phony
+
+import (
+	"sync"
+	"time"
+)
+
+// Gathering is the pending result of a call to Gather, which completes once every Actor has answered, or the timeout has passed.
+type Gathering[T any] struct {
+	future    *Future[[]T]
+	mutex     sync.Mutex
+	results   []T
+	answered  []bool
+	remaining int
+	done      bool
+	timer     *time.Timer
+}
+
+// Gather sends a message from one Actor to each of the actors, asking it to run fn with itself as the argument, and returns a Gathering that collects the results, in the same order as actors.
+// Each message is sent with from as the sender, so any flooded Actor applies backpressure to from, and from is where OnComplete callbacks run.
+// If timeout is positive, then the Gathering completes once it has passed, even if some actors haven't answered, and their results are left as the zero T.
+// Messages that are dropped, e.g. because an Actor has been stopped, count as not answered straight away, so they never hold up the Gathering, and so do calls to fn that panic, if a panic handler recovers them.
+func Gather[T any](from Actor, actors []Actor, fn func(Actor) T, timeout time.Duration) *Gathering[T] {
+	for _, actor := range actors {
+		if actor == nil {
+			panic("tried to send to nil actor")
+		}
+	}
+	if fn == nil {
+		panic("tried to send nil action")
+	}
+	g := &Gathering[T]{
+		future:    NewFuture[[]T](from),
+		results:   make([]T, len(actors)),
+		answered:  make([]bool, len(actors)),
+		remaining: len(actors),
+	}
+	if len(actors) == 0 {
+		g.mutex.Lock()
+		g.finish()
+		return g
+	}
+	if timeout > 0 {
+		g.mutex.Lock()
+		g.timer = time.AfterFunc(timeout, func() {
+			g.mutex.Lock()
+			g.finish()
+		})
+		g.mutex.Unlock()
+	}
+	for idx, actor := range actors {
+		n, a := idx, actor // Because idx and actor get mutated in place
+		inbox := a.inbox()
+		if _, sent := inbox.send(from, func() {
+			var result T
+			var ok bool
+			defer func() { g.answer(n, result, ok) }()
+			result, ok = fn(a), true
+		}); sent {
+			inbox.pressure(from)
+			continue
+		}
+		var zero T
+		g.answer(n, zero, false)
+	}
+	return g
+}
+
+// answer records the result from the Actor at index n, or that it won't answer, if ok is false.
+func (g *Gathering[T]) answer(n int, result T, ok bool) {
+	g.mutex.Lock()
+	if g.done {
+		g.mutex.Unlock()
+		return
+	}
+	if ok {
+		g.results[n], g.answered[n] = result, true
+	}
+	if g.remaining--; g.remaining == 0 {
+		g.finish()
+		return
+	}
+	g.mutex.Unlock()
+}
+
+// finish completes the Gathering, it must be called with the mutex held, which it unlocks.
+func (g *Gathering[T]) finish() {
+	if g.done {
+		g.mutex.Unlock()
+		return
+	}
+	g.done = true
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	results := g.results
+	g.mutex.Unlock()
+	g.future.Set(results)
+}
+
+// Collect waits for the Gathering to complete, and returns the results, in the same order as the actors passed to Gather.
+// It blocks, so it must not be called from an Actor.
+func (g *Gathering[T]) Collect() []T {
+	return g.future.Get()
+}
+
+// OnComplete arranges for callback to be called with the results once the Gathering completes, as a message to the Actor that Gather was called from.
+// If that was nil, then callback runs on whichever goroutine completes the Gathering.
+func (g *Gathering[T]) OnComplete(callback func([]T)) {
+	g.future.OnReady(callback)
+}
+
+// Missing waits for the Gathering to complete, and returns the indexes of the actors that didn't answer in time.
+// It blocks, so it must not be called from an Actor.
+func (g *Gathering[T]) Missing() []int {
+	g.future.Get()
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	var missing []int
+	for idx, ok := range g.answered {
+		if !ok {
+			missing = append(missing, idx)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,91 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGather(t *testing.T) {
+	shards := make([]Inbox, 8)
+	actors := make([]Actor, len(shards))
+	for idx := range shards {
+		actors[idx] = &shards[idx]
+	}
+	index := func(actor Actor) int {
+		for idx := range actors {
+			if actors[idx] == actor {
+				return idx
+			}
+		}
+		return -1
+	}
+	results := Gather(nil, actors, func(actor Actor) int { return index(actor) * 10 }, 0).Collect()
+	if len(results) != len(actors) {
+		t.Fatalf("got %d results, expected %d", len(results), len(actors))
+	}
+	for idx, result := range results {
+		if result != idx*10 {
+			t.Errorf("result %d is %d, expected %d", idx, result, idx*10)
+		}
+	}
+	if results := Gather(nil, nil, func(Actor) int { return 0 }, 0).Collect(); len(results) != 0 {
+		t.Errorf("got %d results from no actors", len(results))
+	}
+}
+
+func TestGatherOnComplete(t *testing.T) {
+	var from, a, b Inbox
+	done := make(chan []string, 1)
+	from.Act(nil, func() {
+		g := Gather(&from, []Actor{&a, &b}, func(actor Actor) string {
+			if actor == &a {
+				return "a"
+			}
+			return "b"
+		}, 0)
+		g.OnComplete(func(results []string) {
+			// This runs on from, so it can use from's state safely
+			done <- results
+		})
+	})
+	if results := <-done; len(results) != 2 || results[0] != "a" || results[1] != "b" {
+		t.Errorf("got %v, expected [a b]", results)
+	}
+}
+
+func TestGatherTimeout(t *testing.T) {
+	var fast, stuck, stopped Inbox
+	stopped.Stop()
+	defer hold(&stuck)()
+	g := Gather(nil, []Actor{&fast, &stuck, &stopped}, func(Actor) int { return 1 }, 20*time.Millisecond)
+	results := g.Collect()
+	if results[0] != 1 || results[1] != 0 || results[2] != 0 {
+		t.Errorf("got %v, expected only the fast actor's result", results)
+	}
+	if missing := g.Missing(); len(missing) != 2 || missing[0] != 1 || missing[1] != 2 {
+		t.Errorf("missing %v, expected [1 2]", missing)
+	}
+	// A stopped actor doesn't hold up a Gathering without a timeout
+	if results := Gather(nil, []Actor{&fast, &stopped}, func(Actor) int { return 1 }, 0).Collect(); results[0] != 1 {
+		t.Errorf("got %v, expected the fast actor's result", results)
+	}
+}
+
+func TestGatherPanic(t *testing.T) {
+	var a, b Inbox
+	b.SetPanicHandler(func(interface{}) {})
+	g := Gather(nil, []Actor{&a, &b}, func(actor Actor) int {
+		if actor == &b {
+			panic("test")
+		}
+		return 1
+	}, 0)
+	finishes(t, func() {
+		if results := g.Collect(); results[0] != 1 || results[1] != 0 {
+			t.Errorf("got %v, expected only the first actor's result", results)
+		}
+		if missing := g.Missing(); len(missing) != 1 || missing[0] != 1 {
+			t.Errorf("missing %v, expected [1]", missing)
+		}
+	})
+}
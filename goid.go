@@ -0,0 +1,34 @@
+package phony
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// TrackWorkerGoID enables or disables recording the goroutine ID of the Inbox's worker, for use with WorkerGoID.
+// The Go runtime doesn't expose goroutine IDs, so they're parsed from the header of runtime.Stack, which costs on the order of a microsecond each time a worker starts.
+// It's meant for debugging, e.g. to match an Actor to its worker in a goroutine dump, and should be left disabled otherwise.
+func (a *Inbox) TrackWorkerGoID(enable bool) {
+	a.trackGoID.Store(enable)
+}
+
+// WorkerGoID returns the goroutine ID of the worker currently running the Inbox's messages, and true if there is one.
+// It returns false if the Inbox is idle, or if tracking wasn't enabled with TrackWorkerGoID when the worker started.
+// The result is inherently racy, since the worker may exit or be replaced at any time.
+func (a *Inbox) WorkerGoID() (uint64, bool) {
+	id := a.goid.Load()
+	return id, id != 0
+}
+
+// curGoID returns the ID of the calling goroutine, by parsing the "goroutine 123 [running]:" header of its stack trace.
+func curGoID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if idx := bytes.IndexByte(b, ' '); idx >= 0 {
+		b = b[:idx]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,28 @@
+package phony
+
+import "testing"
+
+func TestWorkerGoID(t *testing.T) {
+	var a Inbox
+	if _, ok := a.WorkerGoID(); ok {
+		t.Errorf("idle inbox reported a worker")
+	}
+	Block(&a, func() {
+		if _, ok := a.WorkerGoID(); ok {
+			t.Errorf("worker reported without tracking enabled")
+		}
+	})
+	a.TrackWorkerGoID(true)
+	var inside, reported uint64
+	var ok bool
+	Block(&a, func() {
+		inside = curGoID()
+		reported, ok = a.WorkerGoID()
+	})
+	if !ok || inside == 0 || inside != reported {
+		t.Errorf("reported goroutine %d (%v), expected %d", reported, ok, inside)
+	}
+	if outside := curGoID(); outside == inside {
+		t.Errorf("worker and caller share goroutine %d", outside)
+	}
+}
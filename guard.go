@@ -0,0 +1,45 @@
+package phony
+
+// Guard is an Actor that protects a value of type T, which is only accessed by messages run by the Guard's Inbox.
+// The zero value is ready to use, and holds the zero value of T.
+type Guard[T any] struct {
+	Inbox
+	value T
+}
+
+// NewGuard returns a new Guard protecting the provided value.
+func NewGuard[T any](value T) *Guard[T] {
+	return &Guard[T]{value: value}
+}
+
+// Do sends a message to the Guard, asking it to run fn on the guarded value.
+func (g *Guard[T]) Do(from Actor, fn func(*T)) {
+	if fn == nil {
+		panic("tried to send nil action")
+	}
+	g.Act(from, func() { fn(&g.value) })
+}
+
+// DoBlock is like Do, but it waits for fn to finish before returning, so code outside of any Actor can read and modify the guarded value in one step.
+// It uses Block, so it must not be called from an Actor.
+func (g *Guard[T]) DoBlock(fn func(*T)) {
+	if fn == nil {
+		panic("tried to send nil action")
+	}
+	Block(g, func() { fn(&g.value) })
+}
+
+// Get returns a copy of the guarded value.
+// It uses Block, so it must not be called from an Actor.
+func (g *Guard[T]) Get() T {
+	var value T
+	Block(g, func() { value = g.value })
+	return value
+}
+
+// Replace sends a message to the Guard, asking it to swap the whole guarded value for newState.
+// Messages run one at a time, so no other message can observe a partially replaced value.
+// This is the sanctioned way to do a full state replacement, as opposed to modifying the value in place with Do.
+func (g *Guard[T]) Replace(from Actor, newState T) {
+	g.Act(from, func() { g.value = newState })
+}
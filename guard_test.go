@@ -0,0 +1,73 @@
+package phony
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	g := NewGuard(0)
+	for idx := 0; idx < 10; idx++ {
+		g.Do(nil, func(n *int) { *n++ })
+	}
+	if n := g.Get(); n != 10 {
+		t.Errorf("value %d != 10", n)
+	}
+}
+
+func TestGuardReplace(t *testing.T) {
+	type state struct {
+		version int
+		items   []int
+	}
+	var g Guard[state]
+	var wg sync.WaitGroup
+	for idx := 0; idx < 4; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := 0; idx < 256; idx++ {
+				s := g.Get()
+				if len(s.items) != s.version {
+					t.Errorf("version %d has %d items", s.version, len(s.items))
+				}
+				for _, item := range s.items {
+					if item != s.version {
+						t.Errorf("version %d has item %d", s.version, item)
+					}
+				}
+			}
+		}()
+	}
+	for idx := 1; idx <= 256; idx++ {
+		s := state{version: idx}
+		for len(s.items) < idx {
+			s.items = append(s.items, idx)
+		}
+		g.Replace(nil, s)
+	}
+	wg.Wait()
+	if s := g.Get(); s.version != 256 {
+		t.Errorf("final version %d != 256", s.version)
+	}
+}
+
+func TestGuardDoBlock(t *testing.T) {
+	type state struct{ hits, misses int }
+	var g Guard[state]
+	var sender Inbox
+	// Plain Inbox actors and Guards interoperate through the Actor interface
+	sender.Act(nil, func() { g.Do(&sender, func(s *state) { s.hits++ }) })
+	Block(&sender, func() {})
+	var hits int
+	g.DoBlock(func(s *state) {
+		s.misses++
+		hits = s.hits
+	})
+	if hits != 1 {
+		t.Errorf("DoBlock saw %d hits, expected 1", hits)
+	}
+	if s := g.Get(); s.misses != 1 {
+		t.Errorf("DoBlock's change wasn't kept: %+v", s)
+	}
+}
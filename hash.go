@@ -0,0 +1,133 @@
+package phony
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+var hasher atomic.Pointer[func([]byte) uint64] // nil means FNV-1a
+
+// SetDefaultHasher sets the hash function used to assign keys to shards, by routers that weren't given their own hash function, such as a MapActor created with a nil hash.
+// This lets phony's partitioning match an external system's, such as a message broker that partitions by the same keys.
+// Keys are converted to bytes before hashing: strings as their bytes, integers as 8 bytes in little-endian order, floats as the little-endian bits of their float64 value, and anything else as its fmt representation with the %#v verb.
+// The default, restored by passing nil, is 64-bit FNV-1a, which is stable across runs and platforms.
+// Routers capture the hasher when they're created, so changing it doesn't move the keys of existing ones.
+func SetDefaultHasher(hash func([]byte) uint64) {
+	if hash == nil {
+		hasher.Store(nil)
+		return
+	}
+	hasher.Store(&hash)
+}
+
+// defaultHasher returns the hasher set with SetDefaultHasher, or nil for FNV-1a.
+func defaultHasher() func([]byte) uint64 {
+	if hash := hasher.Load(); hash != nil {
+		return *hash
+	}
+	return nil
+}
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// fnv64a returns the 64-bit FNV-1a hash of b.
+func fnv64a(b []byte) uint64 {
+	h := uint64(fnvOffset)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= fnvPrime
+	}
+	return h
+}
+
+// fnv64aString is fnv64a for a string, which avoids converting it to bytes.
+func fnv64aString(s string) uint64 {
+	h := uint64(fnvOffset)
+	for idx := 0; idx < len(s); idx++ {
+		h ^= uint64(s[idx])
+		h *= fnvPrime
+	}
+	return h
+}
+
+// hashKeys returns a function which converts keys to bytes, as described by SetDefaultHasher, and hashes them with hash, or with FNV-1a if hash is nil.
+// Equal keys give equal hashes for strings, numbers, and most other types.
+// FNV-1a hashes strings and numbers in place, without allocating, while any other hash function needs the bytes on the heap.
+func hashKeys[K comparable](hash func([]byte) uint64) func(K) uint64 {
+	if hash == nil {
+		return func(key K) uint64 {
+			if bits, ok := keyBits(key); ok {
+				var buf [8]byte
+				putBits(&buf, bits)
+				return fnv64a(buf[:])
+			}
+			if s, ok := any(key).(string); ok {
+				return fnv64aString(s)
+			}
+			return fnv64aString(fmt.Sprintf("%#v", key))
+		}
+	}
+	return func(key K) uint64 {
+		if bits, ok := keyBits(key); ok {
+			var buf [8]byte
+			putBits(&buf, bits)
+			return hash(buf[:])
+		}
+		if s, ok := any(key).(string); ok {
+			return hash([]byte(s))
+		}
+		return hash([]byte(fmt.Sprintf("%#v", key)))
+	}
+}
+
+// keyBits returns the bits of a numeric key, or false if key isn't a number.
+func keyBits(key any) (bits uint64, ok bool) {
+	switch k := key.(type) {
+	case int:
+		return uint64(k), true
+	case int8:
+		return uint64(k), true
+	case int16:
+		return uint64(k), true
+	case int32:
+		return uint64(k), true
+	case int64:
+		return uint64(k), true
+	case uint:
+		return uint64(k), true
+	case uint8:
+		return uint64(k), true
+	case uint16:
+		return uint64(k), true
+	case uint32:
+		return uint64(k), true
+	case uint64:
+		return k, true
+	case uintptr:
+		return uint64(k), true
+	case float32:
+		return floatBits(float64(k)), true
+	case float64:
+		return floatBits(k), true
+	}
+	return 0, false
+}
+
+// putBits stores bits in buf in little-endian order.
+func putBits(buf *[8]byte, bits uint64) {
+	for idx := range buf {
+		buf[idx] = byte(bits >> (8 * idx))
+	}
+}
+
+// floatBits returns the bits of f, with -0 treated as 0, since the two compare equal.
+func floatBits(f float64) uint64 {
+	if f == 0 {
+		return 0
+	}
+	return math.Float64bits(f)
+}
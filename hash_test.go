@@ -0,0 +1,53 @@
+package phony
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultHasher(t *testing.T) {
+	// FNV-1a of "hello", which must not change between runs or releases
+	if h := hashKeys[string](defaultHasher())("hello"); h != 0xa430d84680aabd0b {
+		t.Errorf("default hash of \"hello\" was %#x", h)
+	}
+	hash := hashKeys[float64](defaultHasher())
+	if hash(0) != hash(math.Copysign(0, -1)) {
+		t.Errorf("equal float keys had different hashes")
+	}
+	type key struct {
+		a int
+		b string
+	}
+	if h := hashKeys[key](defaultHasher()); h(key{1, "x"}) != h(key{1, "x"}) {
+		t.Errorf("equal struct keys had different hashes")
+	}
+}
+
+func TestDefaultHasherAllocs(t *testing.T) {
+	strings, ints := hashKeys[string](defaultHasher()), hashKeys[int](defaultHasher())
+	key := "hello"
+	if n := testing.AllocsPerRun(100, func() { strings(key) }); n != 0 {
+		t.Errorf("hashing a string key made %v allocations", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { ints(42) }); n != 0 {
+		t.Errorf("hashing an int key made %v allocations", n)
+	}
+}
+
+func TestSetDefaultHasher(t *testing.T) {
+	// Send every key to the shard named by its last byte
+	SetDefaultHasher(func(b []byte) uint64 { return uint64(b[len(b)-1] - '0') })
+	m := NewShardedMapActor[string, int](4, nil)
+	SetDefaultHasher(nil)
+	for _, key := range []string{"a0", "b1", "c2", "d3", "e1"} {
+		m.SetBlock(key, 1)
+	}
+	expected := []int{1, 2, 1, 1}
+	for idx, s := range m.shards {
+		Block(s, func() {
+			if len(s.m) != expected[idx] {
+				t.Errorf("shard %d held %d keys, expected %d", idx, len(s.m), expected[idx])
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package phony
+
+import "time"
+
+// Hedger is an Actor that reduces tail latency by duplicating slow requests.
+// Responses are handled by messages run by the Hedger's Inbox, so the race between duplicates is resolved without locks.
+// The zero value is ready to use.
+type Hedger struct {
+	Inbox
+}
+
+// Do sends a message to the Hedger, asking it to start primary and, if no response arrives within hedgeDelay, to also start secondary.
+// Each request function is passed a done callback, which it should call exactly once, from any goroutine, with its response.
+// The first response is passed to onResult, and the slower response is ignored.
+// The request functions and onResult are all run by the Hedger's Inbox, so they should not block, and would typically send a message to another Actor.
+func (h *Hedger) Do(from Actor, primary, secondary func(done func(resp any)), hedgeDelay time.Duration, onResult func(any)) {
+	if primary == nil || secondary == nil {
+		panic("tried to hedge nil request")
+	} else if onResult == nil {
+		panic("tried to hedge with nil result handler")
+	}
+	h.Act(from, func() {
+		var finished bool
+		var timer *time.Timer
+		done := func(resp any) {
+			h.Act(nil, func() {
+				if !finished {
+					finished = true
+					timer.Stop()
+					onResult(resp)
+				}
+			})
+		}
+		timer = time.AfterFunc(hedgeDelay, func() {
+			h.Act(nil, func() {
+				if !finished {
+					secondary(done)
+				}
+			})
+		})
+		primary(done)
+	})
+}
@@ -0,0 +1,52 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHedger(t *testing.T) {
+	var h Hedger
+	primaryDone := make(chan struct{})
+	primary := func(done func(any)) {
+		time.AfterFunc(50*time.Millisecond, func() {
+			done("primary")
+			close(primaryDone)
+		})
+	}
+	secondary := func(done func(any)) {
+		go done("secondary")
+	}
+	var results []any
+	h.Do(nil, primary, secondary, 5*time.Millisecond, func(resp any) {
+		results = append(results, resp)
+	})
+	<-primaryDone
+	Block(&h, func() {})
+	if len(results) != 1 || results[0] != "secondary" {
+		t.Errorf("unexpected results %v", results)
+	}
+}
+
+func TestHedgerFastPrimary(t *testing.T) {
+	var h Hedger
+	var secondaries int
+	results := make(chan any, 2)
+	h.Do(nil, func(done func(any)) {
+		done("primary")
+	}, func(done func(any)) {
+		secondaries++
+		done("secondary")
+	}, 5*time.Millisecond, func(resp any) {
+		results <- resp
+	})
+	if resp := <-results; resp != "primary" {
+		t.Errorf("unexpected result %v", resp)
+	}
+	time.Sleep(20 * time.Millisecond)
+	Block(&h, func() {
+		if secondaries != 0 {
+			t.Errorf("secondary started after primary responded")
+		}
+	})
+}
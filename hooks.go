@@ -0,0 +1,141 @@
+package phony
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// hooks holds the optional callbacks for an Inbox.
+// The whole set is replaced whenever one of them changes, so checking for all of them only costs a single atomic load.
+type hooks struct {
+	age    func(time.Duration)        // called with each message's queueing delay, just before it runs
+	panic  func(interface{})          // called with the value recovered from a panicking message
+	timing func(start, end time.Time) // called with the times each message started and finished running
+}
+
+// updateHooks replaces the Inbox's hooks with a copy that's been modified by update.
+func (a *Inbox) updateHooks(update func(*hooks)) {
+	for {
+		old := a.hooks.Load()
+		h := new(hooks)
+		if old != nil {
+			*h = *old
+		}
+		update(h)
+		if a.hooks.CompareAndSwap(old, h) {
+			return
+		}
+	}
+}
+
+var epoch = time.Now()
+
+// nanotime returns a monotonic timestamp, which is cheaper to store than a time.Time.
+func nanotime() int64 {
+	return int64(time.Since(epoch))
+}
+
+// stamp wraps msg so that it reports its age to the age sink, if there still is one, just before it runs.
+// The timestamp lives in the closure, rather than in every queueElem, so only an Inbox with an age sink pays for it.
+func (a *Inbox) stamp(msg func()) func() {
+	sent := nanotime()
+	return func() {
+		if h := a.hooks.Load(); h != nil && h.age != nil {
+			h.age(time.Duration(nanotime() - sent))
+		}
+		msg()
+	}
+}
+
+// SetAgeSink sets a function to be called with the age of each message, measured from when it was queued until it starts running.
+// This gives queueing latency metrics without any changes to message handlers, at the cost of timestamping each message, which takes an extra allocation per message.
+// The sink runs on the Inbox's worker, just before each message, so it must be fast and non-blocking.
+// Only messages queued after the sink is set are measured, and passing nil removes the sink.
+func (a *Inbox) SetAgeSink(sink func(age time.Duration)) {
+	a.updateHooks(func(h *hooks) { h.age = sink })
+}
+
+// SetTimingSink sets a function to be called with the times that each message started and finished running, e.g. to find the Actors that spend the most time working, or to emit tracing spans.
+// A message that panics is still timed, if a panic handler recovers it.
+// The sink runs on the Inbox's worker, just after each message, so it must be fast and non-blocking.
+// Reading the clock twice per message isn't free, so it's only done while a sink is set, and passing nil removes the sink.
+func (a *Inbox) SetTimingSink(sink func(start, end time.Time)) {
+	a.updateHooks(func(h *hooks) { h.timing = sink })
+}
+
+var defaultPanicHandler atomic.Pointer[func(interface{}, []byte)] // nil means panics crash the program
+
+// SetPanicHandler sets a function to be called, on the Inbox's worker, with the value recovered from any message that panics.
+// The worker then carries on with the rest of the queue as usual, so one bad message doesn't leave the Actor wedged with a backlog that nothing will ever run.
+// The handler runs while the panic is being recovered, so it can call debug.Stack to get the stack trace of the panicking message.
+// Without a handler, the one set with SetDefaultPanicHandler is used, and without either, a panicking message crashes the program, as any other unrecovered panic would.
+// Recovering costs a deferred call for each message, so it's only set up while a handler is installed, and passing nil removes the handler.
+func (a *Inbox) SetPanicHandler(handler func(recovered interface{})) {
+	a.updateHooks(func(h *hooks) { h.panic = handler })
+}
+
+// SetDefaultPanicHandler sets a function to be called with the value recovered from a panicking message, and the stack trace of the panic, for every Inbox that doesn't have its own handler set with SetPanicHandler.
+// As with SetPanicHandler, the worker then carries on with the rest of the queue, and the handler runs on that worker, so it must be fast and non-blocking.
+// Passing nil removes the handler, so panics crash the program again.
+func SetDefaultPanicHandler(handler func(recovered interface{}, stack []byte)) {
+	if handler == nil {
+		defaultPanicHandler.Store(nil)
+		return
+	}
+	defaultPanicHandler.Store(&handler)
+}
+
+// runHandled runs msg, passing the value of any panic to handler, or to the default handler if handler is nil and there's a default.
+func runHandled(msg func(), handler func(interface{})) {
+	if handler != nil {
+		runRecover(msg, handler)
+		return
+	}
+	if handler := defaultPanicHandler.Load(); handler != nil {
+		runDefaultRecover(msg, *handler)
+		return
+	}
+	msg()
+}
+
+// runDefaultRecover runs msg, passing the value and stack trace of any panic to handler.
+func runDefaultRecover(msg func(), handler func(interface{}, []byte)) {
+	defer func() {
+		if r := recover(); r != nil {
+			handler(r, debug.Stack())
+		}
+	}()
+	msg()
+}
+
+// runRecover runs msg, passing the value of any panic to handler.
+func runRecover(msg func(), handler func(interface{})) {
+	defer func() {
+		if r := recover(); r != nil {
+			handler(r)
+		}
+	}()
+	msg()
+}
+
+// observers holds the package-wide callbacks set by SetObserver.
+type observers struct {
+	enqueue func(Actor)
+	dequeue func(Actor)
+}
+
+var observer atomic.Pointer[observers] // nil means no observer
+
+// SetObserver sets functions to be called each time any Inbox queues a message, and each time an Inbox finishes processing one, which is enough for message counters and queue depth gauges.
+// The Actor they're called with is the Inbox itself, so an Inbox embedded in a struct shows up as a pointer to that field, not the struct.
+// onEnqueue is called by the sender, just before the message is added to the queue, so it always happens before the matching onDequeue, which is called by the Inbox's worker after the message has run.
+// Both are called on hot paths, so they must be fast, non-blocking, and safe for concurrent use.
+// Either may be nil, and passing nil for both removes the observer, which leaves a single atomic load per message as the only cost.
+func SetObserver(onEnqueue, onDequeue func(Actor)) {
+	if onEnqueue == nil && onDequeue == nil {
+		observer.Store(nil)
+		return
+	}
+	observer.Store(&observers{enqueue: onEnqueue, dequeue: onDequeue})
+}
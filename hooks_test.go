@@ -0,0 +1,217 @@
+package phony
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAgeSink(t *testing.T) {
+	var a Inbox
+	var ages []time.Duration
+	a.SetAgeSink(func(age time.Duration) { ages = append(ages, age) })
+	release := hold(&a)
+	a.Act(nil, func() {})
+	time.Sleep(20 * time.Millisecond)
+	release()
+	var recorded []time.Duration
+	Block(&a, func() { recorded = append(recorded, ages...) })
+	if len(recorded) != 3 {
+		t.Fatalf("recorded %d ages, expected 3", len(recorded))
+	}
+	ages = recorded
+	if ages[0] > 10*time.Millisecond {
+		t.Errorf("first message waited %v in an empty queue", ages[0])
+	}
+	if ages[1] < 20*time.Millisecond || ages[1] > time.Second {
+		t.Errorf("second message waited %v, expected about 20ms", ages[1])
+	}
+}
+
+func TestAgeSinkRemoved(t *testing.T) {
+	var a Inbox
+	var count int
+	a.SetAgeSink(func(time.Duration) { count++ })
+	Block(&a, func() {})
+	a.SetAgeSink(nil)
+	var before, after int
+	Block(&a, func() { before = count })
+	Block(&a, func() { after = count })
+	if after != before {
+		t.Errorf("sink was called %d times after being removed", after-before)
+	}
+}
+
+func TestPanicHandler(t *testing.T) {
+	var a Inbox
+	var recovered []interface{}
+	a.SetPanicHandler(func(r interface{}) { recovered = append(recovered, r) })
+	var results []int
+	for idx := 0; idx < 8; idx++ {
+		n := idx // Because idx gets mutated in place
+		a.Act(nil, func() {
+			if n%3 == 0 {
+				panic(n)
+			}
+			results = append(results, n)
+		})
+	}
+	Block(&a, func() {})
+	expected := []int{1, 2, 4, 5, 7}
+	if len(results) != len(expected) {
+		t.Fatalf("ran %v, expected %v", results, expected)
+	}
+	for idx := range expected {
+		if results[idx] != expected[idx] {
+			t.Fatalf("ran %v, expected %v", results, expected)
+		}
+	}
+	if len(recovered) != 3 || recovered[0] != 0 || recovered[1] != 3 || recovered[2] != 6 {
+		t.Errorf("recovered %v, expected [0 3 6]", recovered)
+	}
+}
+
+func TestDefaultPanicHandler(t *testing.T) {
+	type report struct {
+		recovered interface{}
+		stack     []byte
+	}
+	reports := make(chan report, 1)
+	SetDefaultPanicHandler(func(r interface{}, stack []byte) { reports <- report{r, stack} })
+	defer SetDefaultPanicHandler(nil)
+	var a Inbox
+	var results []int
+	a.Act(nil, func() { results = append(results, 1) })
+	a.Act(nil, func() { panic("oops") })
+	a.Act(nil, func() { results = append(results, 2) })
+	r := <-reports
+	if r.recovered != "oops" {
+		t.Errorf("recovered %v, expected oops", r.recovered)
+	}
+	if !strings.Contains(string(r.stack), "TestDefaultPanicHandler") {
+		t.Errorf("stack trace doesn't include the panicking message:\n%s", r.stack)
+	}
+	Block(&a, func() {
+		if len(results) != 2 || results[0] != 1 || results[1] != 2 {
+			t.Errorf("ran %v, expected [1 2]", results)
+		}
+	})
+	// A per-Inbox handler takes precedence over the default
+	recovered := make(chan interface{}, 1)
+	a.SetPanicHandler(func(r interface{}) { recovered <- r })
+	a.Act(nil, func() { panic("mine") })
+	if r := <-recovered; r != "mine" {
+		t.Errorf("recovered %v, expected mine", r)
+	}
+	select {
+	case r := <-reports:
+		t.Errorf("default handler also got %v", r.recovered)
+	default:
+	}
+}
+
+func TestObserver(t *testing.T) {
+	var a Inbox
+	var mutex sync.Mutex
+	depth := make(map[Actor]int)
+	var negative bool
+	SetObserver(func(actor Actor) {
+		mutex.Lock()
+		depth[actor]++
+		mutex.Unlock()
+	}, func(actor Actor) {
+		mutex.Lock()
+		if depth[actor]--; depth[actor] < 0 {
+			negative = true
+		}
+		mutex.Unlock()
+	})
+	defer SetObserver(nil, nil)
+	const senders, count = 8, 1000
+	var wg sync.WaitGroup
+	wg.Add(senders)
+	for idx := 0; idx < senders; idx++ {
+		go func() {
+			defer wg.Done()
+			for jdx := 0; jdx < count; jdx++ {
+				a.Act(nil, func() {})
+			}
+		}()
+	}
+	wg.Wait()
+	done := make(chan struct{})
+	a.Act(nil, func() { close(done) })
+	<-done
+	// The observer is called after each message, so wait for the worker to finish with the last one
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		mutex.Lock()
+		n := depth[&a]
+		mutex.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("observed depth is %d after draining, expected 0", n)
+		}
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if negative {
+		t.Errorf("a message was dequeued before it was enqueued")
+	}
+}
+
+func BenchmarkObserverUnset(b *testing.B) {
+	benchmarkObserver(b)
+}
+
+func BenchmarkObserverSet(b *testing.B) {
+	var enqueued, dequeued atomic.Uint64
+	SetObserver(func(Actor) { enqueued.Add(1) }, func(Actor) { dequeued.Add(1) })
+	defer SetObserver(nil, nil)
+	benchmarkObserver(b)
+}
+
+// benchmarkObserver sends b.N messages from one Actor to another, as in BenchmarkSendActor.
+func benchmarkObserver(b *testing.B) {
+	var a, s Inbox
+	done := make(chan struct{})
+	idx := 0
+	var f func()
+	f = func() {
+		if idx < b.N {
+			idx++
+			a.Act(&s, func() {})
+			s.Act(nil, f)
+		} else {
+			a.Act(&s, func() { close(done) })
+		}
+	}
+	s.Act(nil, f)
+	<-done
+}
+
+func TestTimingSink(t *testing.T) {
+	var a Inbox
+	var spans []time.Duration
+	a.SetTimingSink(func(start, end time.Time) { spans = append(spans, end.Sub(start)) })
+	a.SetPanicHandler(func(interface{}) {})
+	a.Act(nil, func() { time.Sleep(10 * time.Millisecond) })
+	a.Act(nil, func() { panic("timed anyway") })
+	Block(&a, func() {
+		// Block's own message is still running, so it hasn't been timed yet
+		if len(spans) != 2 {
+			t.Fatalf("timed %d messages, expected 2", len(spans))
+		}
+		if spans[0] < 10*time.Millisecond {
+			t.Errorf("timed a 10ms message at %v", spans[0])
+		}
+		a.SetTimingSink(nil)
+	})
+	Block(&a, func() {})
+	if len(spans) != 3 {
+		t.Errorf("timed %d messages after removing the sink, expected 3", len(spans))
+	}
+}
@@ -0,0 +1,24 @@
+package phony
+
+import (
+	"log"
+	"runtime"
+)
+
+// WarnIfLeaked is a debugging aid that logs msg if the Inbox is garbage collected while it still has queued messages, which means that work was silently dropped.
+// A running worker keeps its Inbox reachable, so this only happens if a worker exited without finishing its queue, such as when a message calls runtime.Goexit (e.g. via testing.T.FailNow).
+// Calling WarnIfLeaked with an empty msg removes the warning, so it can be cheaply disabled in production builds.
+// This uses runtime.SetFinalizer, so the Inbox must be the first field of the struct it's embedded in (or allocated on its own), and the struct must not have its own finalizer.
+// As with any finalizer, it may never run if the Inbox is part of a reference cycle, e.g. if a queued message captured the Actor.
+func (a *Inbox) WarnIfLeaked(msg string) {
+	if msg == "" {
+		runtime.SetFinalizer(a, nil)
+		return
+	}
+	runtime.SetFinalizer(a, func(a *Inbox) {
+		// Only inspect the Inbox, never store it anywhere, so it isn't resurrected
+		if a.tail.Load() != nil {
+			log.Printf("phony: Inbox leaked with queued messages: %s", msg)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package phony
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWarnIfLeaked(t *testing.T) {
+	var buf syncBuffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	func() {
+		idle := new(Inbox)
+		idle.WarnIfLeaked("idle actor")
+		Block(idle, func() {})
+		leaked := new(Inbox)
+		leaked.WarnIfLeaked("leaked actor")
+		wait := make(chan struct{})
+		leaked.Act(nil, func() {
+			close(wait)
+			runtime.Goexit()
+		})
+		leaked.Act(nil, func() {})
+		<-wait
+	}()
+	for idx := 0; idx < 100 && !strings.Contains(buf.String(), "leaked actor"); idx++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), "leaked actor") {
+		t.Errorf("leaked actor was not reported")
+	}
+	if strings.Contains(buf.String(), "idle actor") {
+		t.Errorf("idle actor was reported as leaked")
+	}
+}
+
+// syncBuffer is a bytes.Buffer that can be written by the finalizer goroutine while the test reads it
+type syncBuffer struct {
+	Inbox
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	Block(b, func() { b.buf.Write(p) })
+	return len(p), nil
+}
+
+func (b *syncBuffer) String() string {
+	var s string
+	Block(b, func() { s = b.buf.String() })
+	return s
+}
@@ -0,0 +1,40 @@
+package phony
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrTooManyActors is returned by NewInboxLimited when the limit set by SetMaxActors has been reached.
+var ErrTooManyActors = errors.New("too many actors")
+
+var maxActors atomic.Int64  // 0 means unlimited
+var liveActors atomic.Int64 // Inboxes created by NewInboxLimited which haven't been stopped
+
+// SetMaxActors sets the maximum number of live Inboxes which can be created by NewInboxLimited.
+// A max of 0 (the default) means that there is no limit.
+// Lowering the limit doesn't affect Inboxes which already exist.
+func SetMaxActors(max int) {
+	maxActors.Store(int64(max))
+}
+
+// LiveActors returns the number of Inboxes which were created by NewInboxLimited and haven't yet been stopped.
+func LiveActors() int {
+	return int(liveActors.Load())
+}
+
+// NewInboxLimited returns a new Inbox, or ErrTooManyActors if the limit set by SetMaxActors has been reached.
+// This lets programs which create Actors dynamically, e.g. one per connection, reject new work instead of growing without bound.
+// The Inbox counts against the limit until Stop is called, so the count never decreases for Inboxes which are simply abandoned.
+// The returned Inbox can be embedded by pointer in the struct that uses it.
+func NewInboxLimited() (*Inbox, error) {
+	for {
+		live, max := liveActors.Load(), maxActors.Load()
+		if max > 0 && live >= max {
+			return nil, ErrTooManyActors
+		}
+		if liveActors.CompareAndSwap(live, live+1) {
+			return &Inbox{limited: true}, nil
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package phony
+
+import "testing"
+
+func TestNewInboxLimited(t *testing.T) {
+	SetMaxActors(LiveActors() + 4)
+	defer SetMaxActors(0)
+	var inboxes []*Inbox
+	for idx := 0; idx < 4; idx++ {
+		a, err := NewInboxLimited()
+		if err != nil {
+			t.Fatalf("failed to create inbox %d: %v", idx, err)
+		}
+		inboxes = append(inboxes, a)
+	}
+	if a, err := NewInboxLimited(); a != nil || err != ErrTooManyActors {
+		t.Errorf("created inbox past the limit")
+	}
+	inboxes[0].Stop()
+	inboxes[0].Stop()
+	a, err := NewInboxLimited()
+	if err != nil {
+		t.Errorf("failed to create inbox after stopping one: %v", err)
+	}
+	inboxes[0] = a
+	if _, err := NewInboxLimited(); err != ErrTooManyActors {
+		t.Errorf("created inbox past the limit after stopping one")
+	}
+	for _, a := range inboxes {
+		a.Stop()
+	}
+}
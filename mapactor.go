@@ -0,0 +1,249 @@
+package phony
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// MapActor is a concurrent map, built from one or more Actors that each own a shard of the keys.
+// Every operation on a key is serialized by the Inbox of the shard that key belongs to, so operations on different shards can run in parallel.
+// Each operation comes in two forms, an asynchronous one which uses Act, and a synchronous one, with a Block suffix, which waits like Block does and so must not be called from an Actor.
+// Asynchronous results are delivered to the sender's Inbox, or run on the shard's worker if the sender is nil.
+type MapActor[K comparable, V any] struct {
+	mutex  sync.RWMutex // write locked by Reshard, while it swaps the layout
+	shards []*mapShard[K, V]
+	hash   func(K) uint64
+}
+
+// mapShard is an Actor that owns part of a MapActor's keys.
+type mapShard[K comparable, V any] struct {
+	Inbox
+	m       map[K]V
+	waiting atomic.Bool // set from when a Reshard creates the shard until the handover of its keys is complete
+	pending []func()    // operations held while waiting, in the order they arrived, only accessed by the shard's worker
+}
+
+// NewMapActor returns a new MapActor with a single shard, which serializes every operation.
+func NewMapActor[K comparable, V any]() *MapActor[K, V] {
+	return NewShardedMapActor[K, V](1, nil)
+}
+
+// NewShardedMapActor returns a new MapActor which spreads its keys across the given number of shards, using hash to choose each key's shard.
+// Equal keys must have equal hashes.
+// If hash is nil, then each key is converted to bytes and hashed by the default hasher, as described by SetDefaultHasher.
+func NewShardedMapActor[K comparable, V any](shards int, hash func(K) uint64) *MapActor[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+	if hash == nil {
+		hash = hashKeys[K](defaultHasher())
+	}
+	m := &MapActor[K, V]{shards: make([]*mapShard[K, V], shards), hash: hash}
+	for idx := range m.shards {
+		m.shards[idx] = &mapShard[K, V]{m: make(map[K]V)}
+	}
+	return m
+}
+
+// shard returns the shard that owns key, the caller must hold the read lock until it has finished sending to the shard.
+func (m *MapActor[K, V]) shard(key K) *mapShard[K, V] {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+	return m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+// Get looks up key and passes the result to callback.
+func (m *MapActor[K, V]) Get(from Actor, key K, callback func(value V, ok bool)) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	s := m.shard(key)
+	s.act(from, func() {
+		value, ok := s.m[key]
+		s.reply(from, func() { callback(value, ok) })
+	})
+}
+
+// Set stores value under key.
+func (m *MapActor[K, V]) Set(from Actor, key K, value V) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	s := m.shard(key)
+	s.act(from, func() { s.m[key] = value })
+}
+
+// Delete removes key, if it's present.
+func (m *MapActor[K, V]) Delete(from Actor, key K) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	s := m.shard(key)
+	s.act(from, func() { delete(s.m, key) })
+}
+
+// Range calls fn for each key and value, one shard at a time, stopping early if fn returns false, and then runs done, if it isn't nil.
+// fn runs on the worker of the shard that owns each key, so it must not Block, and only sees a consistent view of one shard at a time.
+// A Range that overlaps with Reshard sees the shards as they were when it started.
+func (m *MapActor[K, V]) Range(from Actor, fn func(key K, value V) bool, done func()) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	shards := m.shards
+	var visit func(sender Actor, idx int)
+	visit = func(sender Actor, idx int) {
+		s := shards[idx]
+		s.act(sender, func() {
+			for key, value := range s.m {
+				if !fn(key, value) {
+					idx = len(shards)
+					break
+				}
+			}
+			if idx+1 < len(shards) {
+				visit(s, idx+1)
+			} else if done != nil {
+				s.reply(from, done)
+			}
+		})
+	}
+	visit(from, 0)
+}
+
+// GetBlock looks up key and returns the result.
+func (m *MapActor[K, V]) GetBlock(key K) (value V, ok bool) {
+	m.block(key, func(s *mapShard[K, V]) { value, ok = s.m[key] })
+	return
+}
+
+// SetBlock stores value under key, and returns once it's been stored.
+func (m *MapActor[K, V]) SetBlock(key K, value V) {
+	m.block(key, func(s *mapShard[K, V]) { s.m[key] = value })
+}
+
+// DeleteBlock removes key, if it's present, and returns once it's been removed.
+func (m *MapActor[K, V]) DeleteBlock(key K) {
+	m.block(key, func(s *mapShard[K, V]) { delete(s.m, key) })
+}
+
+// RangeBlock calls fn for each key and value, one shard at a time, stopping early if fn returns false.
+// fn runs on the worker of the shard that owns each key, so it must not Block, and only sees a consistent view of one shard at a time.
+// A RangeBlock that overlaps with Reshard sees the shards as they were when it started.
+func (m *MapActor[K, V]) RangeBlock(fn func(key K, value V) bool) {
+	m.mutex.RLock()
+	shards := m.shards
+	m.mutex.RUnlock()
+	for _, s := range shards {
+		more := true
+		done := make(chan struct{})
+		s.act(nil, func() {
+			for key, value := range s.m {
+				if more = fn(key, value); !more {
+					break
+				}
+			}
+			close(done)
+		})
+		<-done
+		if !more {
+			return
+		}
+	}
+}
+
+// Reshard changes the number of shards, moving each key to its shard in the new layout without reordering any operations on it.
+// Operations that were sent before Reshard finish on the old shards, and then each old shard hands its keys over to the new ones.
+// The new shards hold any operations sent after Reshard until every handover is complete, so they briefly lag while the old shards drain, but no worker blocks while it waits.
+// Reshard only holds a lock while it swaps the layout, so it's safe to call from an Actor, and concurrently with other operations.
+func (m *MapActor[K, V]) Reshard(shards int) {
+	if shards < 1 {
+		shards = 1
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	old := m.shards
+	next := make([]*mapShard[K, V], shards)
+	for idx := range next {
+		next[idx] = &mapShard[K, V]{m: make(map[K]V)}
+		next[idx].waiting.Store(true)
+	}
+	// parts[i][j] holds the keys moving from old shard i to new shard j
+	parts := make([][]map[K]V, len(old))
+	var remaining atomic.Int64
+	remaining.Add(int64(len(old)))
+	for idx, s := range old {
+		idx, s := idx, s // Because they get mutated in place
+		// An old shard may still be waiting for a handover of its own, from an earlier Reshard, so this waits its turn like any other operation
+		s.act(nil, func() {
+			part := make([]map[K]V, shards)
+			for key, value := range s.m {
+				j := int(m.hash(key) % uint64(shards))
+				if part[j] == nil {
+					part[j] = make(map[K]V)
+				}
+				part[j][key] = value
+			}
+			parts[idx] = part
+			if remaining.Add(-1) == 0 {
+				// This was the last old shard to finish, so every part is ready to hand over
+				for idx, s := range next {
+					s.handover(parts, idx)
+				}
+			}
+		})
+	}
+	m.shards = next
+}
+
+// handover sends the shard a message that stores its keys from parts, where it's the shard at index idx in the new layout, and then runs the operations that were held until it arrived.
+func (s *mapShard[K, V]) handover(parts [][]map[K]V, idx int) {
+	s.Act(nil, func() {
+		for _, part := range parts {
+			for key, value := range part[idx] {
+				s.m[key] = value
+			}
+		}
+		s.waiting.Store(false)
+		pending := s.pending
+		s.pending = nil
+		for _, fn := range pending {
+			fn()
+		}
+	})
+}
+
+// act sends fn to the shard as a message, which holds fn until the handover of the shard's keys is complete, if it's still waiting for one.
+func (s *mapShard[K, V]) act(from Actor, fn func()) {
+	if !s.waiting.Load() {
+		// The handover has already run, or there never was one, so fn can't overtake it
+		s.Act(from, fn)
+		return
+	}
+	s.Act(from, func() {
+		if s.waiting.Load() {
+			s.pending = append(s.pending, fn)
+			return
+		}
+		fn()
+	})
+}
+
+// block runs fn on the shard that owns key, and waits for it to finish.
+// It only holds the read lock while sending, so it can't hold up a Reshard while it waits.
+func (m *MapActor[K, V]) block(key K, fn func(*mapShard[K, V])) {
+	m.mutex.RLock()
+	s := m.shard(key)
+	done := make(chan struct{})
+	s.act(nil, func() {
+		fn(s)
+		close(done)
+	})
+	m.mutex.RUnlock()
+	<-done
+}
+
+// reply runs fn on the Inbox of the Actor that asked for it, or on the shard's own worker if that Actor is nil, it must only be called from the shard's own messages.
+func (s *mapShard[K, V]) reply(to Actor, fn func()) {
+	if to == nil {
+		fn()
+		return
+	}
+	to.Act(s, fn)
+}
@@ -0,0 +1,128 @@
+package phony
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMapActor(t *testing.T) {
+	for _, m := range []*MapActor[string, int]{
+		NewMapActor[string, int](),
+		NewShardedMapActor[string, int](8, nil),
+	} {
+		for idx := 0; idx < 100; idx++ {
+			m.Set(nil, fmt.Sprint(idx), idx)
+		}
+		m.SetBlock("extra", -1)
+		m.Delete(nil, "0")
+		m.DeleteBlock("1")
+		if value, ok := m.GetBlock("42"); !ok || value != 42 {
+			t.Errorf("got %d, %v, expected 42, true", value, ok)
+		}
+		if _, ok := m.GetBlock("0"); ok {
+			t.Errorf("found a deleted key")
+		}
+		var a Inbox
+		results := make(chan int, 1)
+		m.Get(&a, "extra", func(value int, ok bool) {
+			if !ok {
+				value = 0
+			}
+			results <- value
+		})
+		if value := <-results; value != -1 {
+			t.Errorf("got %d, expected -1", value)
+		}
+		var sum, count int
+		done := make(chan struct{})
+		m.Range(&a, func(key string, value int) bool {
+			sum += value
+			count++
+			return true
+		}, func() { close(done) })
+		<-done
+		if expected := 99*100/2 - 1 - 1; count != 99 || sum != expected {
+			t.Errorf("ranged over %d keys summing to %d, expected 99 keys summing to %d", count, sum, expected)
+		}
+		count = 0
+		m.RangeBlock(func(key string, value int) bool {
+			count++
+			return count < 10
+		})
+		if count != 10 {
+			t.Errorf("ranged over %d keys, expected to stop at 10", count)
+		}
+	}
+}
+
+func TestMapActorReshard(t *testing.T) {
+	const keys, ops = 16, 1000
+	m := NewShardedMapActor[int, int](4, nil)
+	var seen [keys][]int
+	var wg sync.WaitGroup
+	for key := 0; key < keys; key++ {
+		key := key // Because key gets mutated in place
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := 0; idx < ops; idx++ {
+				m.Set(nil, key, idx)
+				m.Get(nil, key, func(value int, ok bool) { seen[key] = append(seen[key], value) })
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, shards := range []int{8, 3, 1, 5, 16, 2, 7} {
+			m.Reshard(shards)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	wg.Wait()
+	<-done
+	for key := 0; key < keys; key++ {
+		if value, _ := m.GetBlock(key); value != ops-1 {
+			t.Errorf("key %d ended with %d, expected %d", key, value, ops-1)
+		}
+		m.block(key, func(*mapShard[int, int]) {
+			if len(seen[key]) != ops {
+				t.Errorf("key %d saw %d gets, expected %d", key, len(seen[key]), ops)
+			}
+			for idx, value := range seen[key] {
+				if value != idx {
+					t.Errorf("key %d saw %d from get %d, operations were reordered", key, value, idx)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMapActorReshardNoBlock(t *testing.T) {
+	m := NewShardedMapActor[int, int](2, nil)
+	for key := 0; key < 16; key++ {
+		m.SetBlock(key, key)
+	}
+	release := hold(m.shards[0])
+	m.Reshard(3)
+	for key := 0; key < 16; key++ {
+		m.Set(nil, key, key+1)
+	}
+	// The new shards hold the operations until the stuck old shard hands over its keys, without blocking their own workers
+	for _, s := range m.shards {
+		for start := time.Now(); s.Len() > 0; time.Sleep(time.Millisecond) {
+			if time.Since(start) > time.Second {
+				t.Fatalf("a new shard's worker is stuck waiting for the handover")
+			}
+		}
+	}
+	release()
+	for key := 0; key < 16; key++ {
+		if value, _ := m.GetBlock(key); value != key+1 {
+			t.Errorf("key %d ended with %d, expected %d", key, value, key+1)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package phony
+
+// OverflowPolicy decides what a bounded Inbox, one with a capacity set by SetCapacity, does with messages that would take it past its capacity.
+type OverflowPolicy uint32
+
+const (
+	// OverflowKeep keeps every message, so the capacity is only checked by Reserve and TrySend, which is the default.
+	OverflowKeep OverflowPolicy = iota
+	// DropNewest discards messages sent while the Inbox is full, so the backlog keeps the oldest messages.
+	DropNewest
+	// DropOldest keeps every message that's sent, but the Inbox's worker skips the oldest messages while the queue is over capacity, so the backlog keeps the newest ones.
+	DropOldest
+)
+
+// SetOverflowPolicy sets what the Inbox does with messages sent with Act, or the other functions built on it, that would take it past its capacity.
+// This suits Actors where stale messages are worthless under overload, such as those processing a sensor feed, where dropping messages is better than applying backpressure that slows down the whole pipeline.
+// Only messages sent by Act and the functions built on it are ever dropped, never the internal messages that Block or backpressure use to signal, and never priority messages.
+// The queue can't be trimmed from the front without a lock, so DropOldest lets the queue grow past capacity and has the worker skip the excess, without running it, as it reaches each message.
+// Concurrent senders may each see room for one more message, so DropNewest can overshoot the capacity by up to the number of senders.
+func (a *Inbox) SetOverflowPolicy(policy OverflowPolicy) {
+	a.overflow.Store(uint32(policy))
+}
+
+// Dropped returns the number of messages the Inbox has discarded because of its OverflowPolicy.
+func (a *Inbox) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// overflows returns true, and counts the messages as dropped, if n new messages should be dropped because of the DropNewest policy.
+// The messages are accepted or dropped together, so a batch is never split by the policy.
+func (a *Inbox) overflows(n int) bool {
+	capacity := a.capacity.Load()
+	if capacity <= 0 || OverflowPolicy(a.overflow.Load()) != DropNewest {
+		return false
+	}
+	// Messages that use up reserved slots were already counted against the capacity
+	unreserved := int64(n) - a.reserved.Load()
+	if unreserved > 0 && int64(a.Len())+unreserved > capacity {
+		a.dropped.Add(uint64(n))
+		return true
+	}
+	return false
+}
+
+// trims returns true if new messages should be wrapped by droppable, because of the DropOldest policy.
+func (a *Inbox) trims() bool {
+	return a.capacity.Load() > 0 && OverflowPolicy(a.overflow.Load()) == DropOldest
+}
+
+// droppable wraps msg so that the worker skips it, running dropped instead if that isn't nil, if the DropOldest policy is trimming the queue when msg reaches the head.
+// The mark lives in the closure, rather than in every queueElem, so only an Inbox with the DropOldest policy pays for it.
+func (a *Inbox) droppable(msg, dropped func()) func() {
+	return func() {
+		if !a.trim() {
+			msg()
+		} else if dropped != nil {
+			dropped()
+		}
+	}
+}
+
+// trim returns true, and counts the message as dropped, if the worker should skip the message at the head of the queue because of the DropOldest policy.
+func (a *Inbox) trim() bool {
+	capacity := a.capacity.Load()
+	if capacity <= 0 || int64(a.Len()) <= capacity || OverflowPolicy(a.overflow.Load()) != DropOldest {
+		return false
+	}
+	a.dropped.Add(1)
+	return true
+}
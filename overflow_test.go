@@ -0,0 +1,100 @@
+package phony
+
+import "testing"
+
+// overflowRun fills a bounded Inbox, while it's stuck on a message, with the numbers 0 to count-1, sent one at a time or as a single batch, and returns the numbers that ran.
+func overflowRun(a *Inbox, count int, batch bool) []int {
+	release := hold(a)
+	var ran []int
+	actions := make([]func(), count)
+	for idx := range actions {
+		n := idx // Because idx gets mutated in place
+		actions[idx] = func() { ran = append(ran, n) }
+	}
+	if batch {
+		a.ActBatch(nil, actions...)
+	} else {
+		for _, action := range actions {
+			a.Act(nil, action)
+		}
+	}
+	release()
+	var result []int
+	Block(a, func() { result = append(result, ran...) })
+	return result
+}
+
+func TestDropNewest(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(4)
+	a.SetOverflowPolicy(DropNewest)
+	// The stuck message takes up one slot
+	ran := overflowRun(&a, 10, false)
+	if len(ran) != 3 || ran[0] != 0 || ran[2] != 2 {
+		t.Errorf("ran %v, expected the oldest 3", ran)
+	}
+	if n := a.Dropped(); n != 7 {
+		t.Errorf("dropped %d messages, expected 7", n)
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(4)
+	a.SetOverflowPolicy(DropOldest)
+	ran := overflowRun(&a, 10, false)
+	// Block's own messages are never dropped, but they count towards the capacity while queued
+	if len(ran) == 0 || ran[len(ran)-1] != 9 {
+		t.Fatalf("ran %v, expected the newest messages", ran)
+	}
+	if len(ran) > 4 {
+		t.Errorf("ran %d messages, expected at most the capacity of 4", len(ran))
+	}
+	if n := a.Dropped(); int(n)+len(ran) != 10 {
+		t.Errorf("dropped %d and ran %d messages, expected 10 in total", n, len(ran))
+	}
+}
+
+func TestOverflowKeep(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(4)
+	if ran := overflowRun(&a, 10, false); len(ran) != 10 {
+		t.Errorf("ran %d messages without an overflow policy, expected 10", len(ran))
+	}
+	if n := a.Dropped(); n != 0 {
+		t.Errorf("dropped %d messages without an overflow policy", n)
+	}
+}
+
+func TestDropNewestBatch(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(2)
+	a.SetOverflowPolicy(DropNewest)
+	if ran := overflowRun(&a, 50, true); len(ran) != 0 {
+		t.Errorf("ran %d messages from a batch that doesn't fit, expected none", len(ran))
+	}
+	if n := a.Dropped(); n != 50 {
+		t.Errorf("dropped %d messages, expected the whole batch of 50", n)
+	}
+	// A batch that fits is accepted as a whole
+	if ran := overflowRun(&a, 1, true); len(ran) != 1 {
+		t.Errorf("ran %d messages from a batch that fits, expected 1", len(ran))
+	}
+}
+
+func TestDropOldestBatch(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(4)
+	a.SetOverflowPolicy(DropOldest)
+	// Block's own messages count towards the capacity, as in TestDropOldest
+	ran := overflowRun(&a, 50, true)
+	if len(ran) == 0 || ran[len(ran)-1] != 49 {
+		t.Fatalf("ran %v, expected the newest messages", ran)
+	}
+	if len(ran) > 4 {
+		t.Errorf("ran %d messages, expected at most the capacity of 4", len(ran))
+	}
+	if n := a.Dropped(); int(n)+len(ran) != 50 {
+		t.Errorf("dropped %d and ran %d messages, expected 50 in total", n, len(ran))
+	}
+}
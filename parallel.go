@@ -0,0 +1,49 @@
+package phony
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ParallelMap applies fn to each input, using at most the given number of worker Actors at once, and returns the results in the same order as the inputs.
+// Each worker takes the next unclaimed input, one message at a time, so uneven costs are balanced across the workers.
+// The workers are idle Inboxes once it returns, so nothing is left running.
+// If workers is less than 1, then fn runs on the calling goroutine instead.
+// It waits for the results, so it must not be called from an Actor.
+func ParallelMap[T, R any](inputs []T, workers int, fn func(T) R) []R {
+	if fn == nil {
+		panic("tried to send nil action")
+	}
+	results := make([]R, len(inputs))
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+	if workers < 1 {
+		// This also covers empty input, which needs no workers
+		for idx, input := range inputs {
+			results[idx] = fn(input)
+		}
+		return results
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	pool := make([]Inbox, workers)
+	for idx := range pool {
+		w := &pool[idx]
+		wg.Add(1)
+		var work func()
+		work = func() {
+			n := int(next.Add(1) - 1)
+			if n >= len(inputs) {
+				wg.Done()
+				return
+			}
+			results[n] = fn(inputs[n])
+			// Send the next item as a new message, so the worker doesn't hog its goroutine
+			w.Act(nil, work)
+		}
+		w.Act(nil, work)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,32 @@
+package phony
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelMap(t *testing.T) {
+	if results := ParallelMap(nil, 4, func(int) int { return 0 }); len(results) != 0 {
+		t.Errorf("got %d results for empty input", len(results))
+	}
+	inputs := make([]int, 10000)
+	for idx := range inputs {
+		inputs[idx] = idx
+	}
+	var running, peak atomic.Int32
+	results := ParallelMap(inputs, 4, func(n int) int {
+		r := running.Add(1)
+		for p := peak.Load(); r > p && !peak.CompareAndSwap(p, r); p = peak.Load() {
+		}
+		defer running.Add(-1)
+		return n * n
+	})
+	for idx, result := range results {
+		if result != idx*idx {
+			t.Fatalf("result %d was %d, expected %d", idx, result, idx*idx)
+		}
+	}
+	if p := peak.Load(); p > 4 {
+		t.Errorf("ran %d calls at once, expected at most 4", p)
+	}
+}
@@ -0,0 +1,58 @@
+package phony
+
+import (
+	"container/list"
+	"sync"
+)
+
+// peerSet is a bounded set of Inboxes, which forgets the least recently used one when it's full.
+type peerSet struct {
+	mutex sync.Mutex
+	limit int
+	order *list.List // of *Inbox, most recently used first
+	index map[*Inbox]*list.Element
+}
+
+// TrackPeers starts recording the distinct Actors that this Inbox sends messages to, meaning sends that name it as the sender, for Peers to report.
+// At most limit peers are remembered, and the least recently used one is forgotten to make room for a new one.
+// A limit of 0 or less stops tracking and forgets the recorded peers.
+// Tracking costs a map lookup under a mutex for each send, so it's off by default.
+func (a *Inbox) TrackPeers(limit int) {
+	if limit <= 0 {
+		a.peers.Store(nil)
+		return
+	}
+	a.peers.Store(&peerSet{limit: limit, order: list.New(), index: make(map[*Inbox]*list.Element)})
+}
+
+// Peers returns the Actors this Inbox has sent messages to since TrackPeers was called, most recently used first, or nil if tracking is off.
+// The Actors are the receiving Inboxes, rather than the structs they're embedded in.
+func (a *Inbox) Peers() []Actor {
+	p := a.peers.Load()
+	if p == nil {
+		return nil
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	peers := make([]Actor, 0, p.order.Len())
+	for e := p.order.Front(); e != nil; e = e.Next() {
+		peers = append(peers, e.Value.(*Inbox))
+	}
+	return peers
+}
+
+// add records a send to peer.
+func (p *peerSet) add(peer *Inbox) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if e, ok := p.index[peer]; ok {
+		p.order.MoveToFront(e)
+		return
+	}
+	p.index[peer] = p.order.PushFront(peer)
+	if p.order.Len() > p.limit {
+		oldest := p.order.Back()
+		p.order.Remove(oldest)
+		delete(p.index, oldest.Value.(*Inbox))
+	}
+}
@@ -0,0 +1,32 @@
+package phony
+
+import "testing"
+
+func TestPeers(t *testing.T) {
+	var sender Inbox
+	var receivers [4]Inbox
+	if peers := sender.Peers(); peers != nil {
+		t.Errorf("got peers before tracking was enabled")
+	}
+	sender.TrackPeers(3)
+	Block(&sender, func() {
+		for idx := range receivers {
+			receivers[idx].Act(&sender, func() {})
+		}
+		receivers[1].Act(&sender, func() {})
+	})
+	peers := sender.Peers()
+	expected := []Actor{&receivers[1], &receivers[3], &receivers[2]}
+	if len(peers) != len(expected) {
+		t.Fatalf("got %d peers, expected %d", len(peers), len(expected))
+	}
+	for idx := range peers {
+		if peers[idx] != expected[idx] {
+			t.Errorf("peer %d was wrong", idx)
+		}
+	}
+	sender.TrackPeers(0)
+	if peers := sender.Peers(); peers != nil {
+		t.Errorf("got peers after tracking was disabled")
+	}
+}
@@ -0,0 +1,51 @@
+// Package phonytest provides helpers for testing code built on phony Actors.
+// It's a separate package so that programs which use phony don't depend on the testing package.
+package phonytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Arceliar/phony"
+)
+
+// Actor is the interface used by the assertions in this package, which any struct that embeds a phony.Inbox satisfies.
+type Actor interface {
+	phony.Actor
+	Busy() bool
+	Len() int
+	Processed() uint64
+}
+
+// Settle is how long the assertions in this package wait for an Actor to reach the expected state before failing the test.
+var Settle = 100 * time.Millisecond
+
+// AssertIdle fails the test if the Actor still has queued messages or a running worker once the Settle period has passed.
+// It's a more direct way of checking that all expected work has finished than sending the Actor an empty message with phony.Block.
+func AssertIdle(tb testing.TB, a Actor) {
+	tb.Helper()
+	if !settle(func() bool { return a.Len() == 0 && !a.Busy() }) {
+		tb.Errorf("actor is not idle: %d queued messages, busy %v", a.Len(), a.Busy())
+	}
+}
+
+// AssertProcessed fails the test if the Actor hasn't finished exactly n messages in total once the Settle period has passed.
+// The count includes internal messages, such as those used to apply backpressure, so it's best used with Actors that only receive messages with a nil sender.
+func AssertProcessed(tb testing.TB, a Actor, n uint64) {
+	tb.Helper()
+	if !settle(func() bool { return a.Processed() == n }) {
+		tb.Errorf("actor processed %d messages, expected %d", a.Processed(), n)
+	}
+}
+
+// settle polls done until it returns true, or the Settle period has passed.
+func settle(done func() bool) bool {
+	deadline := time.Now().Add(Settle)
+	for !done() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package phonytest
+
+import (
+	"testing"
+
+	"github.com/Arceliar/phony"
+)
+
+// recorder is a testing.TB that records failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+}
+
+func TestAssertIdle(t *testing.T) {
+	var a phony.Inbox
+	for idx := 0; idx < 16; idx++ {
+		a.Act(nil, func() {})
+	}
+	AssertIdle(t, &a)
+	AssertProcessed(t, &a, 16)
+	r := &recorder{TB: t}
+	AssertProcessed(r, &a, 17)
+	if !r.failed {
+		t.Errorf("wrong processed count didn't fail")
+	}
+	gate := make(chan struct{})
+	a.Act(nil, func() { <-gate })
+	r = &recorder{TB: t}
+	AssertIdle(r, &a)
+	if !r.failed {
+		t.Errorf("blocked actor was reported idle")
+	}
+	close(gate)
+	AssertIdle(t, &a)
+}
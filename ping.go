@@ -0,0 +1,38 @@
+package phony
+
+import (
+	"context"
+	"time"
+)
+
+// Ping measures how long an Actor takes to get through a trivial message, from when it's queued until it has finished running.
+// This is a cheap liveness and load probe, since a ping time that keeps growing means the Actor is falling behind.
+// It uses Block, so it must not be called from an Actor, and a stopped Actor answers immediately.
+func Ping(a Actor) time.Duration {
+	start := time.Now()
+	Block(a, func() {})
+	return time.Since(start)
+}
+
+// PingContext is like Ping, but it gives up and returns the context's error if the context is done before the Actor answers.
+// The ping goes through the same checks as Act, so unlike Ping, it returns ErrStopped immediately if the Actor has been stopped, or ErrDropped if the ping is dropped for another reason, such as an OverflowPolicy.
+func PingContext(ctx context.Context, a Actor) (time.Duration, error) {
+	if a == nil {
+		panic("tried to send to nil actor")
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	// Buffered, so a late answer doesn't block the Actor
+	done := make(chan struct{}, 1)
+	if _, sent := a.inbox().send(nil, func() { done <- struct{}{} }); !sent {
+		return time.Since(start), a.inbox().refused()
+	}
+	select {
+	case <-done:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package phony
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	time.AfterFunc(20*time.Millisecond, release)
+	if d := Ping(&a); d < 20*time.Millisecond {
+		t.Errorf("ping of a blocked actor took %v, expected at least 20ms", d)
+	}
+	if d := Ping(&a); d > time.Second {
+		t.Errorf("ping of an idle actor took %v", d)
+	}
+}
+
+func TestPingContext(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := PingContext(ctx, &a); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	release()
+	if _, err := PingContext(context.Background(), &a); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPingContextStopped(t *testing.T) {
+	var a Inbox
+	a.Stop()
+	if _, err := PingContext(context.Background(), &a); err != ErrStopped {
+		t.Errorf("got error %v, expected %v", err, ErrStopped)
+	}
+}
@@ -0,0 +1,54 @@
+package phony
+
+// nop is the placeholder message that ActPriority adds to the normal queue.
+var nop = func() {}
+
+// ActPriority is like Act, but the message goes in a separate priority lane, which the Inbox's worker checks before each normal message.
+// That lets control messages, such as a shutdown or reconfiguration, skip ahead of a backlog of normal work, though never ahead of the message that's already running.
+// Messages in the priority lane run in the order they were sent, as do normal messages, but there's no ordering between the two lanes.
+// Backpressure works the same as with Act, so a sender that must never be paused, e.g. one replying to a request, should pass nil as from.
+func (a *Inbox) ActPriority(from Actor, action func()) {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	if !a.admit(from, 1, true) {
+		return
+	}
+	q := a.prepare(from, action, true, nil)
+	if q == nil {
+		return
+	}
+	for {
+		top := a.urgent.Load()
+		q.next.Store(top)
+		if a.urgent.CompareAndSwap(top, q) {
+			break
+		}
+	}
+	// The worker only checks the priority lane while it's running, and only stops once the normal queue is empty, so a placeholder in the normal queue makes sure there's a worker to find q
+	// That includes a worker that's in the middle of shutting down, which either sees the placeholder before it finishes, or fails to and restarts, as with any other message
+	p := elems.Get().(*queueElem)
+	*p = queueElem{msg: nop}
+	a.push(p)
+	a.admitted(from, 1)
+	a.pressure(from)
+}
+
+// runUrgent runs every message in the priority lane, oldest first.
+func (a *Inbox) runUrgent() {
+	// Take the whole stack, and reverse it so the oldest message is first
+	var head *queueElem
+	for q := a.urgent.Swap(nil); q != nil; {
+		next := q.next.Load()
+		q.next.Store(head)
+		head = q
+		q = next
+	}
+	for q := head; q != nil; {
+		next := q.next.Load()
+		a.runMsg(q)
+		*q = queueElem{}
+		elems.Put(q)
+		q = next
+	}
+}
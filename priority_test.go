@@ -0,0 +1,70 @@
+package phony
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestActPriority(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	var order []int
+	for idx := 0; idx < 10; idx++ {
+		n := idx // Because idx gets mutated in place
+		if n%2 == 0 {
+			a.Act(nil, func() { order = append(order, n) })
+		} else {
+			a.ActPriority(nil, func() { order = append(order, n) })
+		}
+	}
+	release()
+	Block(&a, func() {
+		expected := []int{1, 3, 5, 7, 9, 0, 2, 4, 6, 8}
+		if len(order) != len(expected) {
+			t.Fatalf("ran %d messages, expected %d", len(order), len(expected))
+		}
+		for idx := range expected {
+			if order[idx] != expected[idx] {
+				t.Fatalf("messages ran in order %v, expected %v", order, expected)
+			}
+		}
+	})
+}
+
+func TestActPriorityIdle(t *testing.T) {
+	// Priority messages sent to an Inbox that keeps going idle must each start a worker if needed
+	var a Inbox
+	const senders, count = 8, 1000
+	var ran int
+	var wg sync.WaitGroup
+	wg.Add(senders)
+	for idx := 0; idx < senders; idx++ {
+		go func() {
+			defer wg.Done()
+			for jdx := 0; jdx < count; jdx++ {
+				a.ActPriority(nil, func() { ran++ })
+			}
+		}()
+	}
+	wg.Wait()
+	Block(&a, func() {
+		if ran != senders*count {
+			t.Errorf("ran %d priority messages, expected %d", ran, senders*count)
+		}
+	})
+}
+
+func TestActPriorityBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	var sender, receiver Inbox
+	release := hold(&receiver)
+	Block(&sender, func() {
+		receiver.ActPriority(nil, func() {})
+		receiver.ActPriority(&sender, func() {})
+	})
+	if n := sender.PendingBackpressure(); n != 1 {
+		t.Errorf("priority sends applied backpressure %d times, expected 1 for the non-nil sender", n)
+	}
+	release()
+	Block(&sender, func() {})
+}
@@ -0,0 +1,67 @@
+package phony
+
+import "time"
+
+// RateMeter is an Actor that counts events over a sliding time window, to report their recent rate.
+// The window is split into a ring of buckets, which are rotated by the RateMeter's own messages as time passes, so an idle RateMeter needs no timers.
+type RateMeter struct {
+	Inbox
+	width   time.Duration // of each bucket
+	buckets []uint64
+	current int64 // number of the newest bucket, counted in widths since start
+	start   time.Time
+	now     func() time.Time // replaced in tests
+}
+
+// NewRateMeter returns a new RateMeter which measures the rate over the given window, split into the given number of buckets.
+// More buckets make the window slide more smoothly, at the cost of a little memory.
+func NewRateMeter(window time.Duration, buckets int) *RateMeter {
+	if window <= 0 || buckets <= 0 {
+		panic("tried to create RateMeter with non-positive window or buckets")
+	}
+	width := window / time.Duration(buckets)
+	if width <= 0 {
+		width = 1
+	}
+	m := &RateMeter{width: width, buckets: make([]uint64, buckets), now: time.Now}
+	m.start = m.now()
+	return m
+}
+
+// Mark sends a message to the RateMeter, asking it to count one event.
+func (m *RateMeter) Mark(from Actor) {
+	m.Act(from, func() {
+		m.rotate()
+		m.buckets[m.current%int64(len(m.buckets))]++
+	})
+}
+
+// Rate returns the number of events per second over the window.
+// It uses Block, so it must not be called from an Actor.
+func (m *RateMeter) Rate() float64 {
+	var total uint64
+	Block(m, func() {
+		m.rotate()
+		for _, count := range m.buckets {
+			total += count
+		}
+	})
+	window := m.width * time.Duration(len(m.buckets))
+	return float64(total) / window.Seconds()
+}
+
+// rotate clears any buckets which have fallen out of the window since the last event, it must only be called from the RateMeter's own messages.
+func (m *RateMeter) rotate() {
+	n := int64(m.now().Sub(m.start) / m.width)
+	if n <= m.current {
+		return
+	}
+	stale := n - m.current
+	if stale > int64(len(m.buckets)) {
+		stale = int64(len(m.buckets))
+	}
+	for idx := int64(1); idx <= stale; idx++ {
+		m.buckets[(m.current+idx)%int64(len(m.buckets))] = 0
+	}
+	m.current = n
+}
@@ -0,0 +1,33 @@
+package phony
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateMeter(t *testing.T) {
+	var clock fakeClock
+	m := NewRateMeter(time.Second, 10)
+	m.now = clock.time
+	m.start = clock.time()
+	// Mark 10 events every 100ms, for 100 events per second
+	for step := 0; step < 30; step++ {
+		for idx := 0; idx < 10; idx++ {
+			m.Mark(nil)
+		}
+		Block(m, func() {})
+		clock.elapse(100 * time.Millisecond)
+	}
+	if rate := m.Rate(); math.Abs(rate-100) > 10 {
+		t.Errorf("got a rate of %v, expected about 100", rate)
+	}
+	clock.elapse(500 * time.Millisecond)
+	if rate := m.Rate(); math.Abs(rate-50) > 10 {
+		t.Errorf("got a rate of %v half a window after events stopped, expected about 50", rate)
+	}
+	clock.elapse(time.Second)
+	if rate := m.Rate(); rate != 0 {
+		t.Errorf("got a rate of %v a window after events stopped, expected 0", rate)
+	}
+}
@@ -0,0 +1,56 @@
+package phony
+
+// Ref is an Actor that owns a value of type T.
+// The value is only accessed by messages run by the Ref's Inbox, and each Update notifies the stages that were connected to the Ref with Connect.
+type Ref[T any] struct {
+	Inbox
+	value  T
+	stages []func(*T)
+}
+
+// NewRef returns a new Ref holding the provided value.
+func NewRef[T any](value T) *Ref[T] {
+	return &Ref[T]{value: value}
+}
+
+// Update sends a message to the Ref, asking it to run fn on its value and then notify any connected stages.
+func (r *Ref[T]) Update(from Actor, fn func(*T)) {
+	r.Act(from, func() {
+		fn(&r.value)
+		r.notify()
+	})
+}
+
+// Get returns a copy of the Ref's value.
+// It uses Block, so it must not be called from an Actor.
+func (r *Ref[T]) Get() T {
+	var value T
+	Block(r, func() { value = r.value })
+	return value
+}
+
+// notify passes the current value to each connected stage, it must only be called from the Ref's own messages.
+func (r *Ref[T]) notify() {
+	for _, stage := range r.stages {
+		stage(&r.value)
+	}
+}
+
+// Connect links two Refs into a pipeline, so each update to from is passed to transform, and the result is stored in to if transform returns true.
+// Results are delivered with from as the sender, so a slow to applies backpressure to from instead of buffering results unboundedly.
+// The value stored in to counts as an update, so it's passed along to any stages connected to to.
+func Connect[A, B any](from *Ref[A], to *Ref[B], transform func(*A) (B, bool)) {
+	if transform == nil {
+		panic("tried to connect nil transform")
+	}
+	from.Act(nil, func() {
+		from.stages = append(from.stages, func(a *A) {
+			if b, ok := transform(a); ok {
+				to.Act(from, func() {
+					to.value = b
+					to.notify()
+				})
+			}
+		})
+	})
+}
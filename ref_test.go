@@ -0,0 +1,65 @@
+package phony
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRef(t *testing.T) {
+	r := NewRef(1)
+	for idx := 0; idx < 10; idx++ {
+		r.Update(nil, func(n *int) { *n *= 2 })
+	}
+	if n := r.Get(); n != 1024 {
+		t.Errorf("value %d != 1024", n)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	ints := NewRef(0)
+	evens := NewRef("")
+	var last string
+	Connect(ints, evens, func(n *int) (string, bool) {
+		return strconv.Itoa(*n), *n%2 == 0
+	})
+	Connect(evens, NewRef(0), func(s *string) (int, bool) {
+		last = *s
+		return 0, false
+	})
+	for idx := 0; idx < 9; idx++ {
+		ints.Update(nil, func(n *int) { *n++ })
+	}
+	Block(ints, func() {})
+	if s := evens.Get(); s != "8" || last != "8" {
+		t.Errorf("unexpected values %q and %q after updates", s, last)
+	}
+}
+
+func TestConnectBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	from := NewRef(0)
+	to := NewRef(0)
+	var transforms atomic.Int32
+	Connect(from, to, func(n *int) (int, bool) {
+		transforms.Add(1)
+		return *n, true
+	})
+	release := hold(to)
+	Block(from, func() {
+		from.value++
+		from.notify()
+	})
+	from.Update(nil, func(n *int) { *n++ })
+	time.Sleep(10 * time.Millisecond)
+	if n := transforms.Load(); n != 1 {
+		t.Errorf("from ran %d transforms while to was flooded", n)
+	}
+	release()
+	Block(from, func() {})
+	Block(to, func() {})
+	if n := to.Get(); n != 2 {
+		t.Errorf("to has value %d, expected 2", n)
+	}
+}
@@ -0,0 +1,75 @@
+package phony
+
+// SetCapacity sets the number of messages an Inbox is meant to hold at once, which Reserve checks against.
+// By default, Act never drops or refuses a message because of the capacity, so it's a bound that cooperating senders agree to respect, rather than one that's enforced, unless a policy is set with SetOverflowPolicy.
+// A capacity of 0 or less makes the Inbox unbounded, which is the default.
+func (a *Inbox) SetCapacity(capacity int) {
+	if capacity < 0 {
+		capacity = 0
+	}
+	a.capacity.Store(int64(capacity))
+}
+
+// Reserve atomically reserves n slots in a bounded Inbox, returning false, and reserving nothing, if the queued messages plus existing reservations would leave fewer than n slots free.
+// Each message sent to the Inbox afterwards uses up one reserved slot, so a sender that reserved n slots can send an n message burst without exceeding the capacity.
+// Reservations belong to the Inbox, not to the sender that made them, so concurrent senders that didn't reserve anything will also use up reserved slots while any are left.
+// Such sends still count towards the capacity, so the bound holds overall, but a burst that relies on a reservation should come from the only sender to the Inbox, or else be prepared to be slowed down by ordinary backpressure.
+// Slots that won't be used should be returned with Release.
+// An unbounded Inbox always has room, so Reserve returns true without reserving anything.
+func (a *Inbox) Reserve(n int) bool {
+	if n <= 0 {
+		return true
+	}
+	for {
+		capacity := a.capacity.Load()
+		if capacity <= 0 {
+			return true
+		}
+		reserved := a.reserved.Load()
+		if int64(a.Len())+reserved+int64(n) > capacity {
+			return false
+		}
+		if a.reserved.CompareAndSwap(reserved, reserved+int64(n)) {
+			return true
+		}
+	}
+}
+
+// Release returns up to n unused reserved slots, so they're available to other calls to Reserve.
+func (a *Inbox) Release(n int) {
+	for n > 0 {
+		reserved := a.reserved.Load()
+		if reserved <= 0 {
+			return
+		}
+		release := int64(n)
+		if release > reserved {
+			release = reserved
+		}
+		if a.reserved.CompareAndSwap(reserved, reserved-release) {
+			return
+		}
+	}
+}
+
+// TrySend is like Act, but for a bounded Inbox, it drops the message and returns false if the Inbox is full, rather than sending it and relying on backpressure to slow the sender down.
+// That suits load shedding front ends, which would rather refuse work than fall behind, so TrySend never applies backpressure, and from is only used for the Inbox's other checks, such as a per-sender limit.
+// It returns true if the message was queued, which for an unbounded Inbox is whenever Act would have queued it.
+// The check and the send are tied together by reserving a slot first, so concurrent calls to TrySend never overshoot the capacity, but messages sent with Act aren't checked, and can still push the Inbox past it.
+func (a *Inbox) TrySend(from Actor, action func()) bool {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	if a.capacity.Load() <= 0 {
+		_, sent := a.send(from, action)
+		return sent
+	}
+	if !a.Reserve(1) {
+		return false
+	}
+	_, sent := a.send(from, action)
+	if !sent {
+		a.Release(1)
+	}
+	return sent
+}
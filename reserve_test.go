@@ -0,0 +1,113 @@
+package phony
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReserve(t *testing.T) {
+	var a Inbox
+	if !a.Reserve(1000) {
+		t.Error("failed to reserve space in an unbounded Inbox")
+	}
+	a.SetCapacity(8)
+	release := hold(&a)
+	if !a.Reserve(4) {
+		t.Fatal("failed to reserve 4 slots with 7 free")
+	}
+	if a.Reserve(4) {
+		t.Error("reserved 4 slots with only 3 free")
+	}
+	for idx := 0; idx < 4; idx++ {
+		a.Act(nil, func() {})
+	}
+	if a.Reserve(4) {
+		t.Error("reserved 4 slots after filling the reservation")
+	}
+	if !a.Reserve(3) {
+		t.Error("failed to reserve the last 3 slots")
+	}
+	if a.Reserve(1) {
+		t.Error("reserved a slot in a full Inbox")
+	}
+	a.Release(3)
+	if !a.Reserve(1) {
+		t.Error("failed to reserve a released slot")
+	}
+	release()
+	a.Release(1)
+	Block(&a, func() {})
+	if !a.Reserve(7) {
+		t.Error("failed to reserve space after the Inbox drained")
+	}
+}
+
+func TestTrySend(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(4)
+	started, gate := make(chan struct{}), make(chan struct{})
+	if !a.TrySend(nil, func() {
+		close(started)
+		<-gate
+	}) {
+		t.Fatalf("TrySend failed on an empty Inbox")
+	}
+	<-started
+	var ran, accepted int
+	for idx := 0; idx < 10; idx++ {
+		if a.TrySend(nil, func() { ran++ }) {
+			accepted++
+		}
+	}
+	// The running message still counts, so there's only room for 3 more
+	if accepted != 3 {
+		t.Errorf("TrySend accepted %d messages, expected 3", accepted)
+	}
+	close(gate)
+	Block(&a, func() {
+		if ran != accepted {
+			t.Errorf("ran %d messages, but %d were accepted", ran, accepted)
+		}
+	})
+	if !a.TrySend(nil, func() {}) {
+		t.Errorf("TrySend failed after the Inbox drained")
+	}
+}
+
+func TestTrySendUnbounded(t *testing.T) {
+	var a Inbox
+	for idx := 0; idx < 100; idx++ {
+		if !a.TrySend(nil, func() {}) {
+			t.Fatalf("TrySend failed on an unbounded Inbox")
+		}
+	}
+	a.Stop()
+	if a.TrySend(nil, func() {}) {
+		t.Errorf("TrySend succeeded on a stopped Inbox")
+	}
+}
+
+func TestTrySendConcurrent(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(16)
+	release := hold(&a)
+	var accepted atomic.Int64
+	var wg sync.WaitGroup
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jdx := 0; jdx < 100; jdx++ {
+				if a.TrySend(nil, func() {}) {
+					accepted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := accepted.Load(); n != 15 {
+		t.Errorf("concurrent TrySends accepted %d messages, expected exactly 15", n)
+	}
+	release()
+}
@@ -0,0 +1,61 @@
+package phony
+
+// ActToChan sends a message to an Actor, asking it to run fn and send the result to out.
+// It doesn't wait for the result, so the caller can select on out alongside other channels.
+// The Actor's worker blocks on the send to out, so out must be buffered or promptly drained.
+// If fn panics, and a panic handler recovers it, then the zero T is sent, so a caller waiting on out isn't left hanging.
+func ActToChan[T any](actor Actor, fn func() T, out chan<- T) {
+	if actor == nil {
+		panic("tried to send to nil actor")
+	} else if fn == nil {
+		panic("tried to send nil action")
+	}
+	actor.Act(nil, func() {
+		var value T
+		defer func() { out <- value }()
+		value = fn()
+	})
+}
+
+// BlockResult sends a message to an Actor, asking it to run action, and returns the result.
+// Like Block, it must not be called from an Actor, and it returns the zero T without running the action if the Actor has been stopped.
+// It also returns the zero T if the action panics and a panic handler recovers it.
+func BlockResult[T any](actor Actor, action func() T) T {
+	if actor == nil {
+		panic("tried to send to nil actor")
+	} else if action == nil {
+		panic("tried to send nil action")
+	}
+	if blockCheck.Load() {
+		checkBlock(actor)
+	}
+	var result T
+	if actor.inbox().stopped.Load() {
+		return result
+	}
+	// The signal is a separate message, as with Block, so it's still sent if the action panics and a handler recovers it
+	done := stops.Get().(chan struct{})
+	actor.enqueue(func() { result = action() })
+	actor.enqueue(func() { done <- struct{}{} })
+	<-done
+	stops.Put(done)
+	return result
+}
+
+// Ask sends a message from one Actor to another, asking it to run action, and returns a channel that receives the result.
+// The channel is buffered, so the receiving Actor never blocks on it, and the caller can select on it alongside other channels.
+// Since the result arrives on a channel, Ask is mainly for non-Actor code, or for Actors that hand the channel to some other goroutine, since waiting on it from an Actor would block its worker.
+// If action panics, and a panic handler recovers it, then the channel is closed without a result, so a receive gets the zero T.
+func Ask[T any](from Actor, to Actor, action func() T) <-chan T {
+	if to == nil {
+		panic("tried to send to nil actor")
+	} else if action == nil {
+		panic("tried to send nil action")
+	}
+	results := make(chan T, 1)
+	to.Act(from, func() {
+		defer close(results)
+		results <- action()
+	})
+	return results
+}
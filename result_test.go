@@ -0,0 +1,107 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActToChan(t *testing.T) {
+	var a Inbox
+	var count int
+	out := make(chan int, 16)
+	for idx := 0; idx < 16; idx++ {
+		ActToChan(&a, func() int {
+			count++
+			return count
+		}, out)
+	}
+	for idx := 1; idx <= 16; idx++ {
+		if n := <-out; n != idx {
+			t.Errorf("received %d, expected %d", n, idx)
+		}
+	}
+}
+
+func TestBlockResult(t *testing.T) {
+	var a Inbox
+	var log []string
+	for _, s := range []string{"a", "b", "c"} {
+		s := s // Because s gets mutated in place
+		a.Act(nil, func() { log = append(log, s) })
+	}
+	type snapshot struct {
+		entries []string
+		count   int
+	}
+	result := BlockResult(&a, func() snapshot {
+		return snapshot{append([]string(nil), log...), len(log)}
+	})
+	if result.count != 3 || len(result.entries) != 3 || result.entries[2] != "c" {
+		t.Errorf("got %+v, expected all 3 earlier messages to have run", result)
+	}
+}
+
+func TestAsk(t *testing.T) {
+	var a Inbox
+	var count int
+	var results []<-chan map[string]int
+	for idx := 0; idx < 4; idx++ {
+		a.Act(nil, func() { count++ })
+		results = append(results, Ask(nil, &a, func() map[string]int {
+			return map[string]int{"count": count}
+		}))
+	}
+	for idx, result := range results {
+		if m := <-result; m["count"] != idx+1 {
+			t.Errorf("got count %d, expected %d", m["count"], idx+1)
+		}
+	}
+}
+
+// finishes fails the test if f doesn't return within a second, e.g. because it's waiting for a result from an action that panicked.
+func finishes(t *testing.T, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("still waiting a second after the action panicked")
+	}
+}
+
+func TestActToChanPanic(t *testing.T) {
+	var a Inbox
+	a.SetPanicHandler(func(interface{}) {})
+	out := make(chan int, 1)
+	ActToChan(&a, func() int { panic("test") }, out)
+	finishes(t, func() {
+		if n := <-out; n != 0 {
+			t.Errorf("received %d from a panicking action, expected 0", n)
+		}
+	})
+}
+
+func TestBlockResultPanic(t *testing.T) {
+	var a Inbox
+	a.SetPanicHandler(func(interface{}) {})
+	finishes(t, func() {
+		if n := BlockResult(&a, func() int { panic("test") }); n != 0 {
+			t.Errorf("got %d from a panicking action, expected 0", n)
+		}
+	})
+}
+
+func TestAskPanic(t *testing.T) {
+	var a Inbox
+	a.SetPanicHandler(func(interface{}) {})
+	result := Ask(nil, &a, func() int { panic("test") })
+	finishes(t, func() {
+		if _, ok := <-result; ok {
+			t.Errorf("received a result from a panicking action")
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package phony
+
+import "sync/atomic"
+
+// RWRef is an Actor that owns a value of type T, which is meant to be read far more often than it's written.
+// Writes are serialized by the RWRef's Inbox, and the end of each write publishes an immutable snapshot of the value.
+// Reads load the latest snapshot atomically, so they never wait for a message or take a lock.
+type RWRef[T any] struct {
+	Inbox
+	value    T
+	clone    func(T) T
+	snapshot atomic.Pointer[T]
+}
+
+// NewRWRef returns a new RWRef holding the provided value.
+// If T contains pointers, slices, maps, or anything else that a write might modify in place, then clone must return a deep copy of its argument, which is used as the published snapshot.
+// A nil clone means that snapshots are plain copies of the value.
+func NewRWRef[T any](value T, clone func(T) T) *RWRef[T] {
+	r := &RWRef[T]{value: value, clone: clone}
+	r.publish()
+	return r
+}
+
+// Write sends a message to the RWRef, asking it to run fn on its value and then publish a new snapshot.
+func (r *RWRef[T]) Write(from Actor, fn func(*T)) {
+	r.Act(from, func() {
+		fn(&r.value)
+		r.publish()
+	})
+}
+
+// Read returns the most recently published snapshot.
+// It's safe to call from any goroutine, but the result is shared with other readers, so it must not be modified.
+func (r *RWRef[T]) Read() T {
+	if snapshot := r.snapshot.Load(); snapshot != nil {
+		return *snapshot
+	}
+	var zero T
+	return zero
+}
+
+// publish stores a snapshot of the current value, it must only be called from the RWRef's own messages.
+func (r *RWRef[T]) publish() {
+	snapshot := r.value
+	if r.clone != nil {
+		snapshot = r.clone(r.value)
+	}
+	r.snapshot.Store(&snapshot)
+}
@@ -0,0 +1,53 @@
+package phony
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRWRef(t *testing.T) {
+	r := NewRWRef(map[int]int{}, func(m map[int]int) map[int]int {
+		c := make(map[int]int, len(m))
+		for k, v := range m {
+			c[k] = v
+		}
+		return c
+	})
+	var wg sync.WaitGroup
+	for idx := 0; idx < 4; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for last := 0; last < 1024; {
+				m := r.Read()
+				if len(m) < last {
+					t.Errorf("snapshot shrank from %d to %d", last, len(m))
+					return
+				}
+				for k, v := range m {
+					if k != v {
+						t.Errorf("snapshot has mismatched key %d and value %d", k, v)
+					}
+				}
+				last = len(m)
+			}
+		}()
+	}
+	for idx := 0; idx < 1024; idx++ {
+		n := idx // Because idx gets mutated in place
+		r.Write(nil, func(m *map[int]int) { (*m)[n] = n })
+	}
+	wg.Wait()
+}
+
+func TestRWRefZero(t *testing.T) {
+	var r RWRef[int]
+	if n := r.Read(); n != 0 {
+		t.Errorf("zero RWRef read %d", n)
+	}
+	r.Write(nil, func(n *int) { *n = 1 })
+	Block(&r, func() {})
+	if n := r.Read(); n != 1 {
+		t.Errorf("read %d after write, expected 1", n)
+	}
+}
@@ -0,0 +1,255 @@
+package phony
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Scheduler runs the workers that process Inbox messages.
+// By default, every Inbox uses a package-wide Scheduler that never makes a worker wait, while a Scheduler made by NewScheduler limits how many of its Inboxes run at once, to isolate them from the rest of the program.
+type Scheduler struct {
+	workers  int         // maximum number of running workers, or 0 for no limit
+	workerIn chan func() // hands functions to parked workers, unbuffered so a send only succeeds if a worker is waiting
+	parked   atomic.Int32
+	closed   atomic.Bool
+	done     chan struct{} // closed by Close, to wake parked workers so they exit
+	mutex    sync.Mutex
+	queue    []func() // runs waiting for a worker, only used with a limit
+	running  int
+	overflow int          // temporary workers started by ExhaustionOverflow
+	policy   atomic.Int32 // ExhaustionPolicy
+	busy     atomic.Int32 // functions running right now, for Utilization
+	pending  atomic.Int32 // functions scheduled that haven't finished running yet
+	draining atomic.Int32 // calls to Drain waiting for pending to reach 0
+	idle     *sync.Cond   // broadcast on mutex when pending reaches 0 while draining
+}
+
+// defaultScheduler is used by every Inbox that hasn't been attached to another Scheduler.
+var defaultScheduler = newScheduler(0)
+
+// DefaultScheduler returns the Scheduler used by every Inbox that hasn't been attached to another one.
+func DefaultScheduler() *Scheduler {
+	return defaultScheduler
+}
+
+// NewScheduler returns a new Scheduler which runs at most the given number of Inboxes at once, queueing the rest in the order they became ready.
+// That keeps a noisy group of Actors from using more than their share of the CPU, but it also means a worker that blocks, e.g. on backpressure from an Actor that's waiting for a worker from the same Scheduler, can deadlock it, so a limited Scheduler should be used for Actors that don't block on each other.
+// A limit of 0 or less gives a Scheduler that works like the default one, without a limit.
+func NewScheduler(workers int) *Scheduler {
+	if workers < 0 {
+		workers = 0
+	}
+	return newScheduler(workers)
+}
+
+func newScheduler(workers int) *Scheduler {
+	s := &Scheduler{workers: workers, workerIn: make(chan func()), done: make(chan struct{})}
+	s.idle = sync.NewCond(&s.mutex)
+	return s
+}
+
+// Close makes the Scheduler's idle workers exit, instead of staying parked in case there's more work, so a test can check for leaked goroutines once its Actors are finished.
+// Messages sent after Close still run, on goroutines that exit as soon as they're done, so closing a Scheduler that's still in use costs performance but is otherwise safe.
+// Calling Close more than once has no further effect.
+func (s *Scheduler) Close() {
+	if s.closed.CompareAndSwap(false, true) {
+		close(s.done)
+	}
+}
+
+// Drain blocks until the Scheduler has nothing queued for a worker and none of its workers are running, i.e. until every Inbox it was running has emptied its queue, so no scheduled work is lost on shutdown.
+// Actors that keep sending to each other can keep it from returning, so they should be stopped or left idle first, and it must not be called from an Actor that runs on s, since that Actor's own worker would never finish.
+// Drain leaves idle workers parked, so shutdown normally calls Close first, to have them exit too.
+func (s *Scheduler) Drain() {
+	s.mutex.Lock()
+	s.draining.Add(1)
+	for s.pending.Load() > 0 {
+		s.idle.Wait()
+	}
+	s.draining.Add(-1)
+	s.mutex.Unlock()
+}
+
+// DrainScheduler calls Drain on the default Scheduler.
+// A test can use it to make sure everything it sent has run, e.g. before checking for leaked goroutines.
+func DrainScheduler() {
+	defaultScheduler.Drain()
+}
+
+// Utilization returns the fraction of the Scheduler's workers that are running an Inbox right now, as opposed to idle.
+// For a Scheduler with a worker limit, that's out of the limit, so a value that's often at 1 means Inboxes are waiting for workers, and it can go above 1 while ExhaustionInline or ExhaustionOverflow run Inboxes past the limit.
+// Without a limit, it's out of the workers that are running or parked waiting for more work, so a value that's often well below 1 means more workers are parked than are needed.
+// It's a sample of a single moment, so tuning should be based on its average over time.
+func (s *Scheduler) Utilization() float64 {
+	busy := float64(s.busy.Load())
+	if s.workers > 0 {
+		return busy / float64(s.workers)
+	}
+	if total := busy + float64(s.parked.Load()); total > 0 {
+		return busy / total
+	}
+	return 0
+}
+
+// SchedulerUtilization calls Utilization on the default Scheduler.
+func SchedulerUtilization() float64 {
+	return defaultScheduler.Utilization()
+}
+
+// ExhaustionPolicy decides what a Scheduler with a worker limit does with an Inbox that becomes ready to run while all of its workers are busy.
+type ExhaustionPolicy int32
+
+const (
+	// ExhaustionWait queues the Inbox for the next free worker. This is the default.
+	ExhaustionWait ExhaustionPolicy = iota
+	// ExhaustionInline runs the Inbox right away, on the goroutine that sent the message, until its queue is empty.
+	// The sender is held up for that long, and since an Actor that sends the message runs the other Actor nested inside its own message, a chain of Actors sending to each other nests deeper with each one.
+	// The nested Actor can also deadlock on the one below it, e.g. when backpressure pauses it for sending to the Actor whose worker it's running on, so Inline is only safe for Actors that don't send back to their senders.
+	ExhaustionInline
+	// ExhaustionOverflow starts a temporary worker, which runs the Inbox and then exits, instead of picking up queued work.
+	// There can be at most as many temporary workers as the Scheduler's limit at once, and past that the Inbox waits, as with ExhaustionWait.
+	ExhaustionOverflow
+)
+
+// SetWorkerExhaustionPolicy sets what the Scheduler does with an Inbox that becomes ready to run while all of its workers are busy.
+// It has no effect on a Scheduler without a worker limit, which never runs out of workers.
+// An Inbox that yields because of SetMaxRun always waits for a regular worker, since the point of yielding is to let other Inboxes run first.
+func (s *Scheduler) SetWorkerExhaustionPolicy(policy ExhaustionPolicy) {
+	s.policy.Store(int32(policy))
+}
+
+// AttachScheduler makes the Inbox run its messages on workers from s, or from the default Scheduler if s is nil.
+// It takes effect the next time the Inbox starts a worker, so it's normally called before the Inbox is first sent a message.
+func (a *Inbox) AttachScheduler(s *Scheduler) {
+	a.sched.Store(s)
+}
+
+// schedule runs f on a worker goroutine.
+// A yield is always queued for a regular worker on a limited Scheduler, whatever its ExhaustionPolicy.
+func (s *Scheduler) schedule(f func(), yield bool) {
+	s.pending.Add(1)
+	if s.workers > 0 {
+		policy := ExhaustionPolicy(s.policy.Load())
+		if yield {
+			policy = ExhaustionWait
+		}
+		s.scheduleLimited(f, policy)
+		return
+	}
+	// An idle worker that's parked from an earlier run is reused if there is one, and a new goroutine is started otherwise, so there's never any waiting for a free worker.
+	// That matters because a worker can block for a long time, e.g. on backpressure, and a fixed size pool could deadlock if every worker was blocked waiting on work that's stuck behind them.
+	// So only the number of parked workers is bounded, not the number of running ones, which can grow with the number of busy Inboxes, as if every run had its own goroutine.
+	select {
+	case s.workerIn <- f:
+	default:
+		go s.worker(f)
+	}
+}
+
+// worker runs f, and then parks, waiting for more work, unless enough other workers are already parked or the Scheduler is closed.
+// Up to GOMAXPROCS workers stay parked, which avoids starting a new goroutine each time an idle Actor receives a message.
+func (s *Scheduler) worker(f func()) {
+	for {
+		s.run(f)
+		if s.closed.Load() {
+			return
+		}
+		if int(s.parked.Add(1)) > runtime.GOMAXPROCS(0) {
+			s.parked.Add(-1)
+			return
+		}
+		select {
+		case f = <-s.workerIn:
+			s.parked.Add(-1)
+		case <-s.done:
+			s.parked.Add(-1)
+			return
+		}
+	}
+}
+
+// scheduleLimited runs f on a new worker if the Scheduler is below its limit, and follows the policy otherwise.
+func (s *Scheduler) scheduleLimited(f func(), policy ExhaustionPolicy) {
+	s.mutex.Lock()
+	if s.running < s.workers {
+		s.running++
+		s.mutex.Unlock()
+		go s.limitedWorker(f)
+		return
+	}
+	switch {
+	case policy == ExhaustionInline:
+		s.mutex.Unlock()
+		s.run(f)
+		return
+	case policy == ExhaustionOverflow && s.overflow < s.workers:
+		s.overflow++
+		s.mutex.Unlock()
+		go s.overflowWorker(f)
+		return
+	}
+	s.queue = append(s.queue, f)
+	s.mutex.Unlock()
+}
+
+// overflowWorker runs f as a temporary worker, which exits as soon as it's done.
+func (s *Scheduler) overflowWorker(f func()) {
+	s.run(f)
+	s.mutex.Lock()
+	s.overflow--
+	s.mutex.Unlock()
+}
+
+// limitedWorker runs f, and then anything queued, exiting once the queue is empty.
+func (s *Scheduler) limitedWorker(f func()) {
+	for f != nil {
+		s.run(f)
+		s.mutex.Lock()
+		if len(s.queue) > 0 {
+			f = s.queue[0]
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+		} else {
+			f = nil
+			s.running--
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// run runs f on the calling worker, counting it as busy meanwhile, and wakes any callers of Drain if that was the last scheduled function.
+// The count is kept with a defer, so that f calling runtime.Goexit, e.g. through t.FailNow in a test, doesn't leave Drain waiting forever.
+func (s *Scheduler) run(f func()) {
+	s.busy.Add(1)
+	defer func() {
+		s.busy.Add(-1)
+		if s.pending.Add(-1) == 0 && s.draining.Load() > 0 {
+			s.mutex.Lock()
+			s.idle.Broadcast()
+			s.mutex.Unlock()
+		}
+	}()
+	f()
+}
+
+// SetMaxRun limits the Inbox's workers to running n messages each before yielding to the Inbox's Scheduler, even if there are more messages queued.
+// The rest of the queue is then picked up by a new worker, which waits its turn behind other Inboxes on a Scheduler with a worker limit, so a constantly busy Actor can't starve the others there.
+// The Inbox still counts as busy while it waits, so backpressure works as usual, and messages still run one at a time, in order.
+// A limit of 0 or less, the default, lets a worker run until the queue is empty.
+func (a *Inbox) SetMaxRun(n int) {
+	if n < 0 {
+		n = 0
+	}
+	a.maxRun.Store(int64(n))
+}
+
+var syncMode atomic.Bool
+
+// SetSyncMode enables or disables running messages inline, on the goroutine that sends them to an idle Inbox, instead of on a worker from a Scheduler.
+// This is meant for tests, since it makes message processing deterministic: an Act from outside of any Actor has finished by the time it returns, along with anything it caused to be sent to idle Actors, and Block never hops to another goroutine.
+// Messages are still run one at a time, in order, so a message sent to a busy Inbox, including an Actor sending to itself, is queued and runs after the current message returns, as usual.
+// Backpressure is disabled while sync mode is on, since a sender running inline, below the flooded Actor on the same goroutine, would wait for it forever.
+func SetSyncMode(enable bool) {
+	syncMode.Store(enable)
+}
@@ -0,0 +1,385 @@
+package phony
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerLimit(t *testing.T) {
+	s := NewScheduler(2)
+	const count = 8
+	actors := make([]Inbox, count)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for idx := range actors {
+		actors[idx].AttachScheduler(s)
+		actors[idx].Act(nil, func() {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			wg.Done()
+		})
+	}
+	wg.Wait()
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d actors ran at once on a scheduler limited to 2", p)
+	}
+}
+
+func TestSchedulerIsolation(t *testing.T) {
+	s := NewScheduler(1)
+	var noisy Inbox
+	noisy.AttachScheduler(s)
+	release := hold(&noisy)
+	// The only worker on s is stuck, so another Inbox on s has to wait, but one on the default Scheduler doesn't.
+	var queued Inbox
+	queued.AttachScheduler(s)
+	ran := make(chan struct{})
+	queued.Act(nil, func() { close(ran) })
+	var other Inbox
+	Block(&other, func() {})
+	select {
+	case <-ran:
+		t.Error("message ran while the scheduler's only worker was busy")
+	default:
+	}
+	release()
+	<-ran
+}
+
+func TestSchedulerUnlimited(t *testing.T) {
+	s := NewScheduler(0)
+	var a Inbox
+	a.AttachScheduler(s)
+	var count int
+	for idx := 0; idx < 100; idx++ {
+		a.Act(nil, func() { count++ })
+	}
+	Block(&a, func() {})
+	if count != 100 {
+		t.Errorf("ran %d messages, expected 100", count)
+	}
+}
+
+func TestSchedulerClose(t *testing.T) {
+	s := NewScheduler(0)
+	var a Inbox
+	a.AttachScheduler(s)
+	Block(&a, func() {})
+	for deadline := time.Now().Add(time.Second); s.parked.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("worker never parked")
+		}
+	}
+	s.Close()
+	s.Close()
+	for deadline := time.Now().Add(time.Second); s.parked.Load() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d workers still parked after Close", s.parked.Load())
+		}
+	}
+	// The Scheduler still works after Close, it just doesn't keep idle workers around
+	Block(&a, func() {})
+	time.Sleep(10 * time.Millisecond)
+	if n := s.parked.Load(); n != 0 {
+		t.Errorf("%d workers parked on a closed scheduler", n)
+	}
+}
+
+func TestSchedulerSeparatePools(t *testing.T) {
+	s1, s2 := NewScheduler(1), NewScheduler(1)
+	var a1, b1, a2 Inbox
+	a1.AttachScheduler(s1)
+	b1.AttachScheduler(s1)
+	a2.AttachScheduler(s2)
+	release := hold(&a1)
+	// s1's only worker is stuck, which holds up b1, but not a2, which runs on s2's worker
+	ranB1 := make(chan struct{})
+	b1.Act(nil, func() { close(ranB1) })
+	ranA2 := make(chan struct{})
+	a2.Act(nil, func() { close(ranA2) })
+	select {
+	case <-ranA2:
+	case <-time.After(time.Second):
+		t.Fatalf("an Inbox on one scheduler was held up by a busy worker on another")
+	}
+	select {
+	case <-ranB1:
+		t.Errorf("two Inboxes ran at once on a scheduler with one worker")
+	default:
+	}
+	release()
+	<-ranB1
+}
+
+func TestSetMaxRun(t *testing.T) {
+	s := NewScheduler(1)
+	var a, b Inbox
+	var countA, countB, stop atomic.Int64
+	var loop func(*Inbox, *atomic.Int64) func()
+	loop = func(inbox *Inbox, count *atomic.Int64) func() {
+		return func() {
+			if stop.Load() == 0 {
+				count.Add(1)
+				inbox.Act(nil, loop(inbox, count))
+			}
+		}
+	}
+	for _, inbox := range []*Inbox{&a, &b} {
+		inbox.AttachScheduler(s)
+		inbox.SetMaxRun(10)
+	}
+	// Each Actor keeps its queue from ever emptying, so without the cap, the first to start would keep the only worker forever
+	a.Act(nil, loop(&a, &countA))
+	b.Act(nil, loop(&b, &countB))
+	for deadline := time.Now().Add(time.Second); countA.Load() < 1000 || countB.Load() < 1000; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			stop.Store(1)
+			t.Fatalf("actors sharing a worker didn't both make progress: %d and %d messages", countA.Load(), countB.Load())
+		}
+	}
+	stop.Store(1)
+	a.WaitIdle()
+	b.WaitIdle()
+}
+
+func TestSyncMode(t *testing.T) {
+	SetSyncMode(true)
+	defer SetSyncMode(false)
+	var a, b Inbox
+	var order []string
+	a.Act(nil, func() {
+		order = append(order, "a1")
+		// a is busy, so this is queued behind the current message
+		a.Act(nil, func() { order = append(order, "a2") })
+		// b is idle, so this runs inline, before the current message carries on
+		b.Act(&a, func() { order = append(order, "b") })
+		order = append(order, "a1 done")
+	})
+	// Everything has already run, without needing Block to wait for it
+	expected := []string{"a1", "b", "a1 done", "a2"}
+	if len(order) != len(expected) {
+		t.Fatalf("ran %v, expected %v", order, expected)
+	}
+	for idx := range expected {
+		if order[idx] != expected[idx] {
+			t.Fatalf("ran %v, expected %v", order, expected)
+		}
+	}
+	caller := curGoID()
+	Block(&a, func() {
+		if id := curGoID(); id != caller {
+			t.Errorf("Block ran on goroutine %d, expected the caller's goroutine %d", id, caller)
+		}
+	})
+}
+
+func TestSyncModeMaxRun(t *testing.T) {
+	SetSyncMode(true)
+	defer SetSyncMode(false)
+	var a Inbox
+	a.SetMaxRun(1)
+	const count, maxDepth = 1000, 64
+	var n, depth int
+	var step func()
+	step = func() {
+		// Each yield would nest another call to run, so the stack would keep growing unless sync mode ignores the cap
+		var pcs [maxDepth]uintptr
+		if d := runtime.Callers(0, pcs[:]); d > depth {
+			depth = d
+		}
+		if n++; n < count {
+			a.Act(&a, step)
+		}
+	}
+	a.Act(nil, step)
+	if n != count {
+		t.Errorf("ran %d messages, expected %d", n, count)
+	}
+	if depth == maxDepth {
+		t.Errorf("stack kept growing while running messages in sync mode with SetMaxRun")
+	}
+}
+
+func TestSchedulerDrain(t *testing.T) {
+	s := NewScheduler(1)
+	var a, b Inbox
+	a.AttachScheduler(s)
+	b.AttachScheduler(s)
+	var ran atomic.Int32
+	for idx := 0; idx < 10; idx++ {
+		a.Act(nil, func() {
+			time.Sleep(time.Millisecond)
+			ran.Add(1)
+		})
+		b.Act(nil, func() { ran.Add(1) })
+	}
+	s.Drain()
+	if n := ran.Load(); n != 20 {
+		t.Errorf("Drain returned after %d of 20 messages ran", n)
+	}
+	s.Drain() // Returns right away once there's nothing scheduled
+}
+
+func TestDrainSchedulerBurst(t *testing.T) {
+	const count = 100
+	actors := make([]Inbox, count)
+	var ran atomic.Int32
+	for idx := range actors {
+		for i := 0; i < 10; i++ {
+			actors[idx].Act(nil, func() {
+				runtime.Gosched()
+				ran.Add(1)
+			})
+		}
+	}
+	DrainScheduler()
+	if n := ran.Load(); n != count*10 {
+		t.Errorf("DrainScheduler returned after %d of %d messages ran", n, count*10)
+	}
+}
+
+func TestSchedulerExhaustionWait(t *testing.T) {
+	s := NewScheduler(1)
+	var a, b Inbox
+	a.AttachScheduler(s)
+	b.AttachScheduler(s)
+	release := hold(&a)
+	var ran atomic.Bool
+	b.Act(nil, func() { ran.Store(true) })
+	time.Sleep(10 * time.Millisecond)
+	if ran.Load() {
+		t.Errorf("ran while the only worker was busy")
+	}
+	release()
+	s.Drain()
+	if !ran.Load() {
+		t.Errorf("never ran after the worker was freed")
+	}
+}
+
+func TestSchedulerExhaustionInline(t *testing.T) {
+	s := NewScheduler(1)
+	s.SetWorkerExhaustionPolicy(ExhaustionInline)
+	var a, b Inbox
+	a.AttachScheduler(s)
+	b.AttachScheduler(s)
+	release := hold(&a)
+	defer release()
+	var goid uint64
+	b.Act(nil, func() { goid = curGoID() })
+	if goid != curGoID() {
+		t.Errorf("ran on goroutine %d, expected the sender's %d", goid, curGoID())
+	}
+}
+
+func TestSchedulerExhaustionOverflow(t *testing.T) {
+	s := NewScheduler(1)
+	s.SetWorkerExhaustionPolicy(ExhaustionOverflow)
+	var a, b, c Inbox
+	a.AttachScheduler(s)
+	b.AttachScheduler(s)
+	c.AttachScheduler(s)
+	release := hold(&a)
+	// b gets the only temporary worker, so c has to wait
+	releaseB := hold(&b)
+	var ran atomic.Bool
+	c.Act(nil, func() { ran.Store(true) })
+	time.Sleep(10 * time.Millisecond)
+	if ran.Load() {
+		t.Errorf("ran past the limit on temporary workers")
+	}
+	releaseB()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		s.mutex.Lock()
+		n := s.overflow
+		s.mutex.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d temporary workers still running", n)
+		}
+	}
+	if ran.Load() {
+		t.Errorf("a temporary worker picked up queued work")
+	}
+	release()
+	s.Drain()
+	if !ran.Load() {
+		t.Errorf("never ran after the worker was freed")
+	}
+}
+
+func TestSchedulerExhaustionYield(t *testing.T) {
+	s := NewScheduler(1)
+	s.SetWorkerExhaustionPolicy(ExhaustionInline)
+	var a Inbox
+	a.AttachScheduler(s)
+	a.SetMaxRun(1)
+	// A yield that ran inline would nest each worker inside the last, so every message should run at the same stack depth
+	var depths []int
+	for idx := 0; idx < 10; idx++ {
+		a.Act(nil, func() { depths = append(depths, runtime.Callers(0, make([]uintptr, 256))) })
+	}
+	s.Drain()
+	if len(depths) != 10 {
+		t.Fatalf("ran %d messages, expected 10", len(depths))
+	}
+	for _, depth := range depths {
+		if depth != depths[0] {
+			t.Fatalf("yield nested workers, stack depths %v", depths)
+		}
+	}
+}
+
+func TestSchedulerUtilization(t *testing.T) {
+	s := NewScheduler(2)
+	if u := s.Utilization(); u != 0 {
+		t.Errorf("utilization %v before any work, expected 0", u)
+	}
+	var a, b Inbox
+	a.AttachScheduler(s)
+	b.AttachScheduler(s)
+	releaseA, releaseB := hold(&a), hold(&b)
+	if u := s.Utilization(); u != 1 {
+		t.Errorf("utilization %v with every worker busy, expected 1", u)
+	}
+	releaseA()
+	releaseB()
+	s.Drain()
+	if u := s.Utilization(); u != 0 {
+		t.Errorf("utilization %v once idle, expected 0", u)
+	}
+}
+
+func TestSchedulerUtilizationUnlimited(t *testing.T) {
+	s := NewScheduler(0)
+	var a Inbox
+	a.AttachScheduler(s)
+	release := hold(&a)
+	if u := s.Utilization(); u != 1 {
+		t.Errorf("utilization %v with the only worker busy, expected 1", u)
+	}
+	release()
+	s.Drain()
+	for deadline := time.Now().Add(time.Second); s.parked.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("worker never parked")
+		}
+	}
+	if u := s.Utilization(); u != 0 {
+		t.Errorf("utilization %v with the only worker parked, expected 0", u)
+	}
+	s.Close()
+}
@@ -0,0 +1,15 @@
+package phony
+
+// SenderFunc sends a message to an Actor on behalf of the Inbox it was made from, as returned by Sender.
+type SenderFunc func(to Actor, action func())
+
+// Sender returns a function that sends messages to other Actors with this Inbox as the sender, so backpressure from a flooded receiver pauses this Inbox.
+// It saves passing the same from argument to every Act in an Actor's handlers, and avoids accidentally passing the wrong one.
+func (a *Inbox) Sender() SenderFunc {
+	return func(to Actor, action func()) {
+		if to == nil {
+			panic("tried to send to nil actor")
+		}
+		to.Act(a, action)
+	}
+}
@@ -0,0 +1,42 @@
+package phony
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func ExampleInbox_Sender() {
+	var producer, consumer Inbox
+	send := producer.Sender()
+	done := make(chan struct{})
+	producer.Act(nil, func() {
+		for idx := 0; idx < 3; idx++ {
+			n := idx // Because idx gets mutated in place
+			send(&consumer, func() { fmt.Println("got", n) })
+		}
+		send(&consumer, func() { close(done) })
+	})
+	<-done
+	// Output:
+	// got 0
+	// got 1
+	// got 2
+}
+
+func TestSenderBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	var sender, receiver Inbox
+	release := hold(&receiver)
+	send := sender.Sender()
+	Block(&sender, func() { send(&receiver, func() {}) })
+	ran := make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+		t.Errorf("sender didn't pause after sending to a busy receiver")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-ran
+}
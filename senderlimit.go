@@ -0,0 +1,87 @@
+package phony
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLimitedSenders is the number of senders a senderLimiter keeps buckets for, the least recently seen one is forgotten to make room for a new one.
+const maxLimitedSenders = 1024
+
+// senderLimiter holds a token bucket for each recently seen sender to an Inbox.
+type senderLimiter struct {
+	mutex   sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	order   *list.List // of *senderBucket, most recently seen first
+	index   map[*Inbox]*list.Element
+	dropped atomic.Uint64
+	now     func() time.Time // replaced in tests
+}
+
+type senderBucket struct {
+	sender *Inbox
+	tokens float64
+	last   time.Time
+}
+
+// SetPerSenderLimit limits each sender to perSecond messages per second, with bursts of up to burst messages, so one misbehaving sender can't flood the Inbox at the expense of the others.
+// Messages from a sender that's over its limit are dropped, as if the Inbox had been stopped, and counted by SenderLimitDrops.
+// Senders are told apart by the from argument of Act, so messages sent with a nil sender are never limited.
+// A batch sent with ActBatch costs a token for each message, and is dropped as a whole if the sender doesn't have enough, so a batch larger than burst is never accepted.
+// Buckets are kept for a bounded number of recently seen senders, so a sender that's been quiet for long enough may be forgotten, and start again with a full burst.
+// The check costs a map lookup under a mutex for each send, and a perSecond or burst of 0 or less removes the limit.
+func (a *Inbox) SetPerSenderLimit(perSecond float64, burst int) {
+	if perSecond <= 0 || burst <= 0 {
+		a.limiter.Store(nil)
+		return
+	}
+	a.limiter.Store(&senderLimiter{
+		rate:  perSecond,
+		burst: float64(burst),
+		order: list.New(),
+		index: make(map[*Inbox]*list.Element),
+		now:   time.Now,
+	})
+}
+
+// SenderLimitDrops returns the number of messages dropped by the limit set with SetPerSenderLimit, since it was last set.
+func (a *Inbox) SenderLimitDrops() uint64 {
+	if l := a.limiter.Load(); l != nil {
+		return l.dropped.Load()
+	}
+	return 0
+}
+
+// allow takes n tokens from the sender's bucket, and returns false, taking none, if there weren't enough.
+func (l *senderLimiter) allow(sender *Inbox, n int) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	var b *senderBucket
+	if e, ok := l.index[sender]; ok {
+		l.order.MoveToFront(e)
+		b = e.Value.(*senderBucket)
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	} else {
+		b = &senderBucket{sender: sender, tokens: l.burst, last: now}
+		l.index[sender] = l.order.PushFront(b)
+		if l.order.Len() > maxLimitedSenders {
+			oldest := l.order.Back()
+			l.order.Remove(oldest)
+			delete(l.index, oldest.Value.(*senderBucket).sender)
+		}
+	}
+	if b.tokens < float64(n) {
+		l.dropped.Add(uint64(n))
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
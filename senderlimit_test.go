@@ -0,0 +1,76 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPerSenderLimit(t *testing.T) {
+	var clock fakeClock
+	var receiver, abusive, normal Inbox
+	receiver.SetPerSenderLimit(10, 5)
+	receiver.limiter.Load().now = clock.time
+	counts := make(map[*Inbox]int)
+	count := func(sender *Inbox) func() {
+		return func() { counts[sender]++ }
+	}
+	for step := 0; step < 10; step++ {
+		Block(&abusive, func() {
+			for idx := 0; idx < 20; idx++ {
+				receiver.Act(&abusive, count(&abusive))
+			}
+		})
+		Block(&normal, func() { receiver.Act(&normal, count(&normal)) })
+		clock.elapse(100 * time.Millisecond)
+	}
+	Block(&receiver, func() {
+		// A full burst of 5, then 1 per 100ms step
+		if counts[&abusive] != 5+9 {
+			t.Errorf("received %d messages from the abusive sender, expected 14", counts[&abusive])
+		}
+		if counts[&normal] != 10 {
+			t.Errorf("received %d messages from the normal sender, expected 10", counts[&normal])
+		}
+	})
+	if n := receiver.SenderLimitDrops(); n != 200-14 {
+		t.Errorf("dropped %d messages, expected %d", n, 200-14)
+	}
+	receiver.SetPerSenderLimit(0, 0)
+	Block(&abusive, func() { receiver.Act(&abusive, count(&abusive)) })
+	Block(&receiver, func() {
+		if counts[&abusive] != 15 {
+			t.Errorf("message was dropped after removing the limit")
+		}
+	})
+}
+
+func TestPerSenderLimitBatch(t *testing.T) {
+	var clock fakeClock
+	var receiver, sender Inbox
+	receiver.SetPerSenderLimit(1, 3)
+	receiver.limiter.Load().now = clock.time
+	var count int
+	batch := func(n int) []func() {
+		actions := make([]func(), n)
+		for idx := range actions {
+			actions[idx] = func() { count++ }
+		}
+		return actions
+	}
+	Block(&sender, func() {
+		// Bigger than the burst, so it can never be accepted
+		receiver.ActBatch(&sender, batch(100)...)
+		receiver.ActBatch(&sender, batch(2)...)
+		// Only 1 token is left
+		receiver.ActBatch(&sender, batch(2)...)
+		receiver.ActBatch(&sender, batch(1)...)
+	})
+	Block(&receiver, func() {
+		if count != 3 {
+			t.Errorf("received %d messages, expected 3", count)
+		}
+	})
+	if n := receiver.SenderLimitDrops(); n != 102 {
+		t.Errorf("dropped %d messages, expected 102", n)
+	}
+}
@@ -0,0 +1,96 @@
+package phony
+
+import "time"
+
+// Sequencer is an Actor that runs numbered messages in order, buffering any that arrive early until the ones before them have run.
+// If the next message in sequence is still missing once the gap timeout has passed, the Sequencer reports the gap and skips ahead to the earliest buffered message.
+// Buffering and reordering are managed by messages run by the Sequencer's Inbox.
+type Sequencer struct {
+	Inbox
+	next    uint64
+	pending map[uint64]func()
+	timeout time.Duration
+	onGap   func(first, last uint64)
+	gap     *gapTimer                               // nil if no gap is being timed
+	after   func(time.Duration, func()) func() bool // Starts a timer and returns its stop function, replaced in tests
+}
+
+// A pending gap timeout, identified by pointer so stale timers can be ignored
+type gapTimer struct {
+	stop func() bool
+}
+
+// NewSequencer returns a Sequencer which expects first to be the first sequence number.
+// If a gap in the sequence lasts for longer than timeout, then onGap is called with the first and last missing numbers, inclusive, and those numbers are skipped.
+// The onGap function is run by the Sequencer's Inbox, so it should not block.
+func NewSequencer(first uint64, timeout time.Duration, onGap func(first, last uint64)) *Sequencer {
+	if onGap == nil {
+		panic("tried to create Sequencer with nil onGap function")
+	}
+	return &Sequencer{
+		next:    first,
+		pending: make(map[uint64]func()),
+		timeout: timeout,
+		onGap:   onGap,
+		after: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+	}
+}
+
+// Deliver sends a message to the Sequencer, asking it to run action once every message numbered before seq has run or been skipped.
+// The action is run by the Sequencer's Inbox, so it should not block.
+// Messages with a number that has already run or been skipped, or that duplicate one that's already buffered, are dropped.
+func (s *Sequencer) Deliver(from Actor, seq uint64, action func()) {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	s.Act(from, func() {
+		if _, isIn := s.pending[seq]; isIn || seq < s.next {
+			return
+		}
+		s.pending[seq] = action
+		s.release()
+	})
+}
+
+// release runs buffered messages for as long as they're in sequence, and keeps the gap timer running while any are left waiting.
+func (s *Sequencer) release() {
+	progress := false
+	for action, isIn := s.pending[s.next]; isIn; action, isIn = s.pending[s.next] {
+		delete(s.pending, s.next)
+		s.next++
+		progress = true
+		action()
+	}
+	if s.gap != nil && (progress || len(s.pending) == 0) {
+		s.gap.stop()
+		s.gap = nil
+	}
+	if s.gap == nil && len(s.pending) > 0 {
+		timer := new(gapTimer)
+		s.gap = timer
+		timer.stop = s.after(s.timeout, func() {
+			s.Act(nil, func() {
+				// The timer may have fired after being replaced but before being stopped
+				if s.gap == timer {
+					s.gap = nil
+					s.skip()
+				}
+			})
+		})
+	}
+}
+
+// skip reports the current gap, moves past it to the earliest buffered message, and releases what it can from there.
+func (s *Sequencer) skip() {
+	earliest := s.next
+	for seq := range s.pending {
+		if earliest == s.next || seq < earliest {
+			earliest = seq
+		}
+	}
+	s.onGap(s.next, earliest-1)
+	s.next = earliest
+	s.release()
+}
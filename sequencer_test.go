@@ -0,0 +1,45 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSequencer(t *testing.T) {
+	var clock fakeClock
+	var gaps [][2]uint64
+	s := NewSequencer(1, time.Second, func(first, last uint64) {
+		gaps = append(gaps, [2]uint64{first, last})
+	})
+	s.after = clock.afterFunc
+	var released []uint64
+	for _, seq := range []uint64{3, 1, 5, 2, 1, 4, 9, 7} {
+		n := seq // Because seq gets mutated in place
+		s.Deliver(nil, n, func() { released = append(released, n) })
+	}
+	Block(s, func() {})
+	clock.elapse(500 * time.Millisecond)
+	Block(s, func() {})
+	Block(s, func() {
+		if len(released) != 5 || len(gaps) != 0 {
+			t.Errorf("released %v with gaps %v, expected 1 to 5 with no gaps", released, gaps)
+		}
+	})
+	// 6 is missing, so 7 is released after the timeout, and then 8 is missing
+	clock.elapse(time.Second)
+	Block(s, func() {})
+	clock.elapse(time.Second)
+	Block(s, func() {})
+	expected := []uint64{1, 2, 3, 4, 5, 7, 9}
+	if len(released) != len(expected) {
+		t.Fatalf("released %v, expected %v", released, expected)
+	}
+	for idx := range expected {
+		if released[idx] != expected[idx] {
+			t.Fatalf("released %v, expected %v", released, expected)
+		}
+	}
+	if len(gaps) != 2 || gaps[0] != [2]uint64{6, 6} || gaps[1] != [2]uint64{8, 8} {
+		t.Errorf("got gaps %v, expected 6 and 8", gaps)
+	}
+}
@@ -0,0 +1,21 @@
+package phony
+
+// Len returns the number of messages in the Inbox which haven't finished running, including the one that's currently running, if any.
+// This includes internal messages, such as those used to apply backpressure.
+// Messages may be added or finished concurrently, so the result is only approximate, but it's never negative, and it reaches 0 once the Inbox is idle.
+func (a *Inbox) Len() int {
+	// Load processed first, so a concurrent enqueue can only make the result too large
+	processed := a.processed.Load()
+	return int(a.enqueued.Load() - processed)
+}
+
+// Busy returns true if a worker is currently running the Inbox's messages.
+// The result is a racy snapshot, which may be out of date by the time it's used.
+func (a *Inbox) Busy() bool {
+	return a.busy.Load()
+}
+
+// Processed returns the total number of messages the Inbox has finished running, including internal messages, such as those used to apply backpressure.
+func (a *Inbox) Processed() uint64 {
+	return a.processed.Load()
+}
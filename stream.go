@@ -0,0 +1,82 @@
+package phony
+
+// Stream sends a sequence of messages from one Actor to another, using credits granted by the receiver for flow control, instead of per-message backpressure.
+// Each message sent uses up one credit, and the receiver grants more credits as it processes messages, so the number of messages in flight never exceeds the credits granted.
+// The Stream's state belongs to the sender, so Send, OnReady, Credits, and Pending must only be called from the sender's own messages.
+type Stream struct {
+	sender   Actor
+	receiver Actor
+	credits  int
+	pending  []func()
+	ready    func()
+}
+
+// NewStream returns a new Stream from sender to receiver, which can send initialCredits messages before the receiver needs to grant more.
+func NewStream(sender, receiver Actor, initialCredits int) *Stream {
+	if sender == nil || receiver == nil {
+		panic("tried to create stream with nil actor")
+	}
+	return &Stream{sender: sender, receiver: receiver, credits: initialCredits}
+}
+
+// Send delivers item to the receiver if a credit is available, or else buffers it on the sender's side until the receiver grants more.
+// Buffered items are delivered in order, ahead of any later Send.
+func (s *Stream) Send(item func()) {
+	if item == nil {
+		panic("tried to send nil action")
+	}
+	if s.credits > 0 && len(s.pending) == 0 {
+		s.credits--
+		s.receiver.Act(nil, item)
+		return
+	}
+	s.pending = append(s.pending, item)
+}
+
+// Grant sends a message to the sender, giving it n more credits, and delivering any buffered items that they cover.
+// Buffered items are delivered to the receiver together in one message, which makes a saturated Stream cheaper than sending each item separately.
+// It's meant to be called by the receiver, typically once it's processed some batch of items.
+func (s *Stream) Grant(n int) {
+	s.sender.Act(nil, func() {
+		s.credits += n
+		defer s.notify()
+		count := len(s.pending)
+		if count > s.credits {
+			count = s.credits
+		}
+		if count == 0 {
+			return
+		}
+		s.credits -= count
+		batch := s.pending[:count:count]
+		s.pending = s.pending[count:]
+		s.receiver.Act(nil, func() {
+			for _, item := range batch {
+				item()
+			}
+		})
+	})
+}
+
+// OnReady sets a function for the sender to run whenever it receives new credits, after any buffered items have been delivered.
+// This lets a producer send only while Credits is positive, and resume when the receiver catches up, instead of buffering items.
+func (s *Stream) OnReady(ready func()) {
+	s.ready = ready
+}
+
+// notify runs the ready function, if any, from the sender's own messages.
+func (s *Stream) notify() {
+	if s.ready != nil {
+		s.ready()
+	}
+}
+
+// Credits returns the number of items that can be sent before the receiver needs to grant more.
+func (s *Stream) Credits() int {
+	return s.credits
+}
+
+// Pending returns the number of items buffered while waiting for credits.
+func (s *Stream) Pending() int {
+	return len(s.pending)
+}
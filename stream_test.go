@@ -0,0 +1,68 @@
+package phony
+
+import "testing"
+
+func TestStream(t *testing.T) {
+	var sender, receiver Inbox
+	s := NewStream(&sender, &receiver, 8)
+	var inFlight, maxInFlight, received int
+	var results []int
+	done := make(chan struct{})
+	Block(&sender, func() {
+		for idx := 0; idx < 1024; idx++ {
+			n := idx // Because idx gets mutated in place
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			s.Send(func() {
+				results = append(results, n)
+				sender.Act(nil, func() { inFlight-- })
+				if received++; received == 1024 {
+					close(done)
+				} else if received%4 == 0 {
+					s.Grant(4)
+				}
+			})
+		}
+	})
+	<-done
+	if len(results) != 1024 {
+		t.Fatalf("received %d items, expected 1024", len(results))
+	}
+	for idx, n := range results {
+		if n != idx {
+			t.Errorf("value %d != index %d", n, idx)
+		}
+	}
+	Block(&sender, func() {
+		if s.Pending() != 0 {
+			t.Errorf("%d items still pending", s.Pending())
+		}
+	})
+}
+
+func BenchmarkStream(b *testing.B) {
+	var sender, receiver Inbox
+	s := NewStream(&sender, &receiver, 64)
+	done := make(chan struct{})
+	received := 0
+	item := func() {
+		if received++; received == b.N {
+			close(done)
+		} else if received%32 == 0 {
+			s.Grant(32)
+		}
+	}
+	idx := 0
+	produce := func() {
+		for ; idx < b.N && s.Credits() > 0; idx++ {
+			s.Send(item)
+		}
+	}
+	sender.Act(nil, func() {
+		s.OnReady(produce)
+		produce()
+	})
+	<-done
+}
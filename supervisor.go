@@ -0,0 +1,132 @@
+package phony
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Supervisor is an Actor that owns a value of type S, like a Guard, but rebuilds the value whenever one of its messages panics, on the assumption that the panic may have left it in an inconsistent state.
+// The panicking message is treated as finished, so the messages queued behind it run as usual, against the fresh value, and none are lost or run twice.
+// If the value has to be rebuilt too many times within a window, the Supervisor gives up, reporting the failure and stopping its Inbox, so a persistent fault doesn't turn into an endless crash loop.
+// Otherwise, the Supervisor stays the same Actor throughout, so anything holding a reference to it keeps sending to a live Actor.
+type Supervisor[S any] struct {
+	Inbox
+	state       *S
+	newState    func() *S
+	maxRestarts int
+	window      time.Duration
+	restarts    []time.Time  // within the window, oldest first
+	count       atomic.Int64 // len(restarts), so Restarts can still read it once the Supervisor has stopped
+	onGiveUp    func(recovered interface{})
+	backoff     time.Duration // before the first rebuild, doubled for each further rebuild within the window
+	maxBackoff  time.Duration
+	pending     []func(*S)       // messages that arrived during a backoff, while state is nil
+	now         func() time.Time // replaced in tests
+}
+
+// NewSupervisor returns a new Supervisor, with a value built by newState.
+// After a panic, the value is rebuilt by newState, unless that would be more than maxRestarts rebuilds within the window, in which case onGiveUp is called, on the Supervisor's worker, with the recovered value from the panic, and the Supervisor is stopped.
+// A negative maxRestarts never gives up, and onGiveUp may be nil.
+// Together with SetBackoff, that covers the usual restart policies: always restarting, giving up after too many restarts, and backing off between restarts.
+func NewSupervisor[S any](newState func() *S, maxRestarts int, window time.Duration, onGiveUp func(recovered interface{})) *Supervisor[S] {
+	if newState == nil {
+		panic("tried to create Supervisor with nil newState")
+	}
+	s := &Supervisor[S]{
+		state:       newState(),
+		newState:    newState,
+		maxRestarts: maxRestarts,
+		window:      window,
+		onGiveUp:    onGiveUp,
+		now:         time.Now,
+	}
+	s.SetPanicHandler(s.rebuild)
+	return s
+}
+
+// Do sends a message to the Supervisor, asking it to run fn on the supervised value.
+func (s *Supervisor[S]) Do(from Actor, fn func(*S)) {
+	if fn == nil {
+		panic("tried to send nil action")
+	}
+	s.Act(from, func() { s.run(fn) })
+}
+
+// SetBackoff sends a message to the Supervisor, asking it to wait before each rebuild, starting with initial, and doubling for each further rebuild within the window, up to max.
+// Messages that arrive during a backoff are held, and run in order, against the fresh value, once it's been rebuilt, without holding up the Supervisor's worker in the mean time.
+// A max below initial gives a constant backoff, and an initial backoff of 0, the default, rebuilds the value immediately.
+func (s *Supervisor[S]) SetBackoff(from Actor, initial, max time.Duration) {
+	if max < initial {
+		max = initial
+	}
+	s.Act(from, func() {
+		s.backoff, s.maxBackoff = initial, max
+	})
+}
+
+// run runs fn on the supervised value, or holds it until the value has been rebuilt, if there's a backoff in progress.
+func (s *Supervisor[S]) run(fn func(*S)) {
+	if s.state == nil {
+		s.pending = append(s.pending, fn)
+		return
+	}
+	fn(s.state)
+}
+
+// Restarts returns the number of times the value has been rebuilt within the current window.
+// Once the Supervisor has given up and stopped, it returns the number as of then, so the restarts that led to giving up can still be seen.
+// It uses Block to forget old restarts first, so it must not be called from an Actor.
+func (s *Supervisor[S]) Restarts() int {
+	Block(s, s.prune)
+	return int(s.count.Load())
+}
+
+// rebuild is the Inbox's panic handler, it rebuilds the value, or gives up if there have been too many restarts.
+func (s *Supervisor[S]) rebuild(recovered interface{}) {
+	s.prune()
+	if s.maxRestarts >= 0 && len(s.restarts) >= s.maxRestarts {
+		s.Stop()
+		if s.onGiveUp != nil {
+			s.onGiveUp(recovered)
+		}
+		return
+	}
+	s.restarts = append(s.restarts, s.now())
+	s.count.Store(int64(len(s.restarts)))
+	if s.backoff <= 0 {
+		s.state = s.newState()
+		return
+	}
+	delay := s.backoff
+	for idx := 1; idx < len(s.restarts) && delay < s.maxBackoff; idx++ {
+		delay *= 2
+	}
+	if delay > s.maxBackoff {
+		delay = s.maxBackoff
+	}
+	s.state = nil
+	s.ActAfter(nil, delay, s.resume)
+}
+
+// resume rebuilds the value after a backoff, and then runs the messages that were held during it.
+// Those go in the priority lane, so they run before anything that's already queued, and keep their order.
+func (s *Supervisor[S]) resume() {
+	s.state = s.newState()
+	pending := s.pending
+	s.pending = nil
+	for _, fn := range pending {
+		f := fn // Because fn gets mutated in place
+		s.ActPriority(nil, func() { s.run(f) })
+	}
+}
+
+// prune forgets restarts that are older than the window.
+func (s *Supervisor[S]) prune() {
+	cutoff := s.now().Add(-s.window)
+	n := 0
+	for n < len(s.restarts) && !s.restarts[n].After(cutoff) {
+		n++
+	}
+	s.restarts = append(s.restarts[:0], s.restarts[n:]...)
+	s.count.Store(int64(len(s.restarts)))
+}
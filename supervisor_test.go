@@ -0,0 +1,138 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSupervisor(t *testing.T) {
+	type state struct{ count int }
+	var builds int
+	s := NewSupervisor(func() *state {
+		builds++
+		return new(state)
+	}, -1, time.Hour, nil)
+	var counts []int
+	for idx := 0; idx < 6; idx++ {
+		n := idx // Because idx gets mutated in place
+		s.Do(nil, func(st *state) {
+			st.count++
+			if n == 2 {
+				panic("corrupted")
+			}
+			counts = append(counts, st.count)
+		})
+	}
+	Block(s, func() {
+		// The panic discards the first value, so counting starts again from the message after it
+		expected := []int{1, 2, 1, 2, 3}
+		if len(counts) != len(expected) {
+			t.Fatalf("ran %v, expected %v", counts, expected)
+		}
+		for idx := range expected {
+			if counts[idx] != expected[idx] {
+				t.Fatalf("ran %v, expected %v", counts, expected)
+			}
+		}
+		if builds != 2 {
+			t.Errorf("built the value %d times, expected 2", builds)
+		}
+	})
+	if n := s.Restarts(); n != 1 {
+		t.Errorf("Restarts returned %d, expected 1", n)
+	}
+}
+
+func TestSupervisorGiveUp(t *testing.T) {
+	type state struct{}
+	gaveUp := make(chan interface{}, 1)
+	s := NewSupervisor(func() *state { return new(state) }, 2, time.Hour, func(r interface{}) { gaveUp <- r })
+	var ran int
+	for idx := 0; idx < 3; idx++ {
+		n := idx // Because idx gets mutated in place
+		s.Do(nil, func(*state) { panic(n) })
+	}
+	s.Do(nil, func(*state) { ran++ })
+	if r := <-gaveUp; r != 2 {
+		t.Errorf("gave up with %v, expected the third panic", r)
+	}
+	// Messages that were already queued still run, but later ones are dropped
+	s.Do(nil, func(*state) { ran++ })
+	Drain(s)
+	if ran != 1 {
+		t.Errorf("ran %d messages after giving up, expected 1", ran)
+	}
+	if n := s.Restarts(); n != 2 {
+		t.Errorf("Restarts returned %d after giving up, expected 2", n)
+	}
+}
+
+func TestSupervisorWindow(t *testing.T) {
+	type state struct{}
+	now := time.Time{}
+	s := NewSupervisor(func() *state { return new(state) }, 1, time.Minute, func(interface{}) {
+		t.Errorf("gave up even though the restarts were outside the window")
+	})
+	s.now = func() time.Time { return now }
+	for idx := 0; idx < 3; idx++ {
+		s.Do(nil, func(*state) {
+			now = now.Add(2 * time.Minute)
+			panic("again")
+		})
+	}
+	if n := s.Restarts(); n != 1 {
+		t.Errorf("Restarts returned %d, expected 1 within the window", n)
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	type state struct{ generation int }
+	var generation int
+	s := NewSupervisor(func() *state {
+		generation++
+		return &state{generation}
+	}, -1, time.Hour, nil)
+	s.SetBackoff(nil, 10*time.Millisecond, 20*time.Millisecond)
+	type result struct {
+		n, generation int
+		at            time.Time
+	}
+	results := make(chan result, 10)
+	start := time.Now()
+	for idx := 0; idx < 6; idx++ {
+		n := idx // Because idx gets mutated in place
+		s.Do(nil, func(st *state) {
+			if n == 1 || n == 3 {
+				panic(n)
+			}
+			results <- result{n, st.generation, time.Now()}
+		})
+	}
+	// Each panic rebuilds the value after a backoff, which doubles for the second restart, so later messages are delayed by the total
+	expected := []result{{0, 1, start}, {2, 2, start.Add(10 * time.Millisecond)}, {4, 3, start.Add(30 * time.Millisecond)}, {5, 3, start.Add(30 * time.Millisecond)}}
+	for _, e := range expected {
+		r := <-results
+		if r.n != e.n || r.generation != e.generation {
+			t.Fatalf("message %d ran against generation %d, expected message %d against generation %d", r.n, r.generation, e.n, e.generation)
+		}
+		if r.at.Before(e.at) {
+			t.Errorf("message %d ran %v after starting, before its backoff ended", r.n, r.at.Sub(start))
+		}
+	}
+	if n := s.Restarts(); n != 2 {
+		t.Errorf("Restarts returned %d, expected 2", n)
+	}
+}
+
+func TestSupervisorAlways(t *testing.T) {
+	type state struct{}
+	s := NewSupervisor(func() *state { return new(state) }, -1, time.Hour, func(interface{}) {
+		t.Errorf("gave up with an unlimited restart policy")
+	})
+	for idx := 0; idx < 100; idx++ {
+		s.Do(nil, func(*state) { panic("again") })
+	}
+	if n := s.Restarts(); n != 100 {
+		t.Errorf("Restarts returned %d, expected 100", n)
+	}
+}
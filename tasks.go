@@ -0,0 +1,73 @@
+package phony
+
+import "container/heap"
+
+// TaskScheduler is an Actor that runs submitted tasks one at a time, highest priority first, breaking ties by the order they were submitted.
+// Submissions are added to a heap owned by the TaskScheduler's Inbox, and each task runs as its own step, so tasks submitted while one runs are considered before the next is chosen.
+type TaskScheduler struct {
+	Inbox
+	tasks     taskHeap
+	submitted uint64 // count of tasks ever submitted, for breaking ties
+	stepping  bool   // whether a step message is queued
+}
+
+type scheduledTask struct {
+	priority int
+	seq      uint64
+	task     func()
+}
+
+// taskHeap implements heap.Interface, with the highest priority, then earliest submitted, task at the top.
+type taskHeap []scheduledTask
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x any)   { *h = append(*h, x.(scheduledTask)) }
+func (h *taskHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = scheduledTask{}
+	*h = old[:len(old)-1]
+	return last
+}
+
+// Submit sends a message to the TaskScheduler, asking it to run task once every pending task with a higher priority, or the same priority but submitted earlier, has run.
+// The task is run by the TaskScheduler's Inbox, so it should not block.
+func (s *TaskScheduler) Submit(from Actor, priority int, task func()) {
+	if task == nil {
+		panic("tried to send nil action")
+	}
+	s.Act(from, func() {
+		s.submitted++
+		heap.Push(&s.tasks, scheduledTask{priority, s.submitted, task})
+		if !s.stepping {
+			s.stepping = true
+			s.Act(nil, s.step)
+		}
+	})
+}
+
+// Pending returns the number of submitted tasks that haven't run yet.
+// It uses Block, so it must not be called from an Actor.
+func (s *TaskScheduler) Pending() int {
+	var n int
+	Block(s, func() { n = len(s.tasks) })
+	return n
+}
+
+// step runs the highest priority task, and queues another step if there are more.
+func (s *TaskScheduler) step() {
+	t := heap.Pop(&s.tasks).(scheduledTask)
+	t.task()
+	if len(s.tasks) > 0 {
+		s.Act(nil, s.step)
+	} else {
+		s.stepping = false
+	}
+}
@@ -0,0 +1,44 @@
+package phony
+
+import "testing"
+
+func TestTaskScheduler(t *testing.T) {
+	var s TaskScheduler
+	var order []string
+	started, gate := make(chan struct{}), make(chan struct{})
+	s.Submit(nil, 0, func() {
+		close(started)
+		<-gate
+	})
+	<-started
+	for _, task := range []struct {
+		priority int
+		name     string
+	}{
+		{1, "low a"},
+		{5, "high a"},
+		{1, "low b"},
+		{3, "mid"},
+		{5, "high b"},
+		{-1, "lowest"},
+	} {
+		name := task.name // Because task gets mutated in place
+		s.Submit(nil, task.priority, func() { order = append(order, name) })
+	}
+	done := make(chan struct{})
+	s.Submit(nil, -2, func() { close(done) })
+	close(gate)
+	<-done
+	if n := s.Pending(); n != 0 {
+		t.Errorf("%d tasks still pending", n)
+	}
+	expected := []string{"high a", "high b", "mid", "low a", "low b", "lowest"}
+	if len(order) != len(expected) {
+		t.Fatalf("ran %v, expected %v", order, expected)
+	}
+	for idx := range expected {
+		if order[idx] != expected[idx] {
+			t.Fatalf("ran %v, expected %v", order, expected)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package phony
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Ticker is a handle for a repeating message, as returned by ActEvery.
+type Ticker struct {
+	inbox     *Inbox
+	from      Actor
+	period    time.Duration
+	action    func()
+	next      time.Time             // when the next tick is due, only accessed by the timer queue
+	timer     atomic.Pointer[Timer] // for the next tick
+	queued    atomic.Bool           // whether a tick has been sent and hasn't run yet
+	cancelled atomic.Bool
+}
+
+// ActEvery sends a message to the Inbox once every period, until the returned Ticker is cancelled.
+// Ticks are driven by the same queue as ActAfter, so there's no goroutine per Ticker, and nothing to leak once it's cancelled.
+// At most one tick is queued at a time, so if the Inbox falls behind, ticks that come due while the last one is still queued are skipped, rather than piling up.
+// The schedule stays fixed, so a skipped tick doesn't shift the ones after it, which are still due at whole multiples of period from the start.
+// Each tick is sent with Act, so from has backpressure applied whenever a tick finds the Inbox flooded.
+// A tick that the Inbox drops, e.g. because of its OverflowPolicy, is skipped like any other, without holding up later ones.
+func (a *Inbox) ActEvery(from Actor, period time.Duration, action func()) *Ticker {
+	if action == nil {
+		panic("tried to send nil action")
+	} else if period <= 0 {
+		panic("tried to create Ticker with non-positive period")
+	}
+	t := &Ticker{inbox: a, from: from, period: period, action: action}
+	t.next = time.Now().Add(period)
+	t.schedule()
+	return t
+}
+
+// Cancel stops the Ticker, so no further ticks are sent, and returns true if it was still running.
+// A tick that's already queued doesn't run either, so it's safe to cancel a Ticker from its own action, or from anywhere else, any number of times.
+func (t *Ticker) Cancel() bool {
+	if !t.cancelled.CompareAndSwap(false, true) {
+		return false
+	}
+	t.timer.Load().Cancel()
+	return true
+}
+
+// schedule sets a Timer for the next tick.
+func (t *Ticker) schedule() {
+	timer := &Timer{when: t.next, index: -1, fire: t.tick}
+	t.timer.Store(timer)
+	if t.cancelled.Load() {
+		// Cancel may have missed the new Timer, so don't start it
+		return
+	}
+	timers.add(timer)
+}
+
+// tick runs on the timer queue when a tick is due, sends it unless the last one is still queued, and then schedules the next one.
+func (t *Ticker) tick() {
+	if t.cancelled.Load() {
+		return
+	}
+	if t.queued.CompareAndSwap(false, true) {
+		// A tick that's refused, or dropped once queued, must clear queued too, or no later tick is ever sent
+		ready := func() { t.queued.Store(false) }
+		_, sent := t.inbox.sendDroppable(t.from, func() {
+			ready()
+			if !t.cancelled.Load() {
+				t.action()
+			}
+		}, ready)
+		if sent {
+			t.inbox.pressure(t.from)
+		} else {
+			ready()
+		}
+	}
+	now := time.Now()
+	t.next = t.next.Add(t.period)
+	if !t.next.After(now) {
+		// The timer queue fell behind, so skip to the next tick that's still in the future
+		t.next = t.next.Add((now.Sub(t.next)/t.period + 1) * t.period)
+	}
+	t.schedule()
+}
@@ -0,0 +1,169 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActEvery(t *testing.T) {
+	var a Inbox
+	const period, target = 5 * time.Millisecond, 5
+	var count int
+	done := make(chan struct{})
+	start := time.Now()
+	ticker := a.ActEvery(nil, period, func() {
+		if count++; count == target {
+			close(done)
+		}
+	})
+	// Count ticks until there are enough, rather than over a fixed time, so a slow machine only makes the test slower
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("ticked fewer than %d times in 5s with a %v period", target, period)
+	}
+	if elapsed := time.Since(start); elapsed < target*period {
+		t.Errorf("ticked %d times in %v, faster than the %v period", target, elapsed, period)
+	}
+	if !ticker.Cancel() {
+		t.Errorf("failed to cancel a running ticker")
+	}
+	if ticker.Cancel() {
+		t.Errorf("cancelled a ticker twice")
+	}
+	var ticks int
+	Block(&a, func() { ticks = count })
+	time.Sleep(20 * time.Millisecond)
+	Block(&a, func() {
+		if count != ticks {
+			t.Errorf("ticked %d more times after Cancel", count-ticks)
+		}
+	})
+}
+
+func TestActEveryCoalesce(t *testing.T) {
+	var a Inbox
+	release := hold(&a)
+	var count int
+	ticker := a.ActEvery(nil, time.Millisecond, func() { count++ })
+	defer ticker.Cancel()
+	time.Sleep(30 * time.Millisecond)
+	if n := a.Len(); n > 2 {
+		t.Errorf("%d messages queued while stuck, expected the stuck one and at most one tick", n)
+	}
+	release()
+	Block(&a, func() {
+		if count > 1 {
+			t.Errorf("ran %d ticks that were queued while stuck, expected at most 1", count)
+		}
+	})
+}
+
+// waitTick fails the test unless ticked receives a tick within a second.
+func waitTick(t *testing.T, ticked chan struct{}) {
+	t.Helper()
+	select {
+	case <-ticked:
+	case <-time.After(time.Second):
+		t.Fatalf("Ticker stopped ticking after a tick was dropped")
+	}
+}
+
+func TestActEveryDropNewest(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(1)
+	a.SetOverflowPolicy(DropNewest)
+	release := hold(&a)
+	ticked := make(chan struct{}, 1)
+	ticker := a.ActEvery(nil, time.Millisecond, func() {
+		select {
+		case ticked <- struct{}{}:
+		default:
+		}
+	})
+	defer ticker.Cancel()
+	for start := time.Now(); a.Dropped() == 0; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("no tick was dropped while the Inbox was full")
+		}
+	}
+	release()
+	waitTick(t, ticked)
+}
+
+func TestActEveryDropOldest(t *testing.T) {
+	var a Inbox
+	a.SetCapacity(1)
+	a.SetOverflowPolicy(DropOldest)
+	release := hold(&a)
+	ticked := make(chan struct{}, 1)
+	ticker := a.ActEvery(nil, time.Millisecond, func() {
+		select {
+		case ticked <- struct{}{}:
+		default:
+		}
+	})
+	defer ticker.Cancel()
+	for start := time.Now(); a.Len() < 2; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("no tick was queued")
+		}
+	}
+	// The tick is over capacity once another message is queued behind it, so the worker skips it
+	a.Act(nil, func() {})
+	release()
+	waitTick(t, ticked)
+	if a.Dropped() == 0 {
+		t.Errorf("the queued tick wasn't dropped")
+	}
+}
+
+func TestActEveryCancelFromTick(t *testing.T) {
+	var a Inbox
+	var count int
+	var ticker *Ticker
+	done := make(chan struct{})
+	Block(&a, func() {
+		ticker = a.ActEvery(nil, time.Millisecond, func() {
+			if count++; count == 3 {
+				ticker.Cancel()
+				close(done)
+			}
+		})
+	})
+	<-done
+	time.Sleep(10 * time.Millisecond)
+	Block(&a, func() {
+		if count != 3 {
+			t.Errorf("ticked %d times, expected it to stop at 3", count)
+		}
+	})
+}
+
+func TestActEveryCancelConcurrent(t *testing.T) {
+	var a Inbox
+	ticker := a.ActEvery(nil, time.Millisecond, func() {})
+	time.Sleep(5 * time.Millisecond)
+	results := make(chan bool, 8)
+	for idx := 0; idx < cap(results); idx++ {
+		go func() { results <- ticker.Cancel() }()
+	}
+	var cancelled int
+	for idx := 0; idx < cap(results); idx++ {
+		if <-results {
+			cancelled++
+		}
+	}
+	if cancelled != 1 {
+		t.Errorf("%d concurrent calls to Cancel returned true, expected 1", cancelled)
+	}
+	// Nothing is left in the timer queue to keep the Ticker alive
+	time.Sleep(5 * time.Millisecond)
+	Block(&timers, func() {
+		for _, timer := range timers.heap {
+			if timer == ticker.timer.Load() {
+				t.Errorf("cancelled ticker is still in the timer queue")
+			}
+		}
+	})
+}
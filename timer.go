@@ -0,0 +1,139 @@
+package phony
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// Timer is a handle for a delayed message, as returned by ActAfter.
+type Timer struct {
+	state atomic.Uint32 // timerPending, timerFired, or timerCancelled
+	when  time.Time
+	seq   uint64 // for ordering timers that are due at the same time
+	index int    // in the timer queue's heap, or -1 if it isn't in the heap
+	fire  func()
+}
+
+const (
+	timerPending uint32 = iota
+	timerFired
+	timerCancelled
+)
+
+// Cancel stops the Timer, so its message is never sent, and returns true if it was still pending.
+// It returns false if the message was already sent, or the Timer was already cancelled.
+func (t *Timer) Cancel() bool {
+	if !t.state.CompareAndSwap(timerPending, timerCancelled) {
+		return false
+	}
+	// Remove it from the queue now, rather than holding on to it until it's due
+	timers.Act(nil, func() {
+		if t.index >= 0 {
+			heap.Remove(&timers.heap, t.index)
+			timers.reset()
+		}
+	})
+	return true
+}
+
+// timerQueue is an Actor that owns every pending Timer, and uses a single time.Timer to wake up when the earliest one is due.
+// Timers are sent in order of when they're due, and in the order they were created if they're due at the same time.
+type timerQueue struct {
+	Inbox
+	heap  timerHeap
+	seq   uint64
+	timer *time.Timer
+	next  time.Time // when timer is set to fire, zero if it isn't set
+}
+
+var timers timerQueue
+
+// timerHeap implements heap.Interface, with the earliest due Timer at the top.
+type timerHeap []*Timer
+
+func (h timerHeap) Len() int { return len(h) }
+func (h timerHeap) Less(i, j int) bool {
+	if !h[i].when.Equal(h[j].when) {
+		return h[i].when.Before(h[j].when)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+func (h *timerHeap) Push(x any) {
+	t := x.(*Timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	t.index = -1
+	return t
+}
+
+// ActAfter sends a message to the Inbox once d has passed, and returns a Timer that can cancel it before then.
+// The message is sent with Act when the Timer fires, so backpressure depends on the state of the Inbox at that point, not when ActAfter was called.
+// Messages that are due at the same time are sent in the order ActAfter was called.
+// No goroutine is held while a Timer is pending, so cancelled or forgotten Timers don't leak anything but their memory until they're due.
+func (a *Inbox) ActAfter(from Actor, d time.Duration, action func()) *Timer {
+	if action == nil {
+		panic("tried to send nil action")
+	}
+	t := &Timer{when: time.Now().Add(d), index: -1, fire: func() { a.Act(from, action) }}
+	timers.add(t)
+	return t
+}
+
+// add sends a message to the queue, asking it to track t.
+func (q *timerQueue) add(t *Timer) {
+	q.Act(nil, func() {
+		if t.state.Load() != timerPending {
+			return
+		}
+		q.seq++
+		t.seq = q.seq
+		heap.Push(&q.heap, t)
+		q.reset()
+	})
+}
+
+// expire sends every Timer that's due, and then resets the wake up time for the rest.
+func (q *timerQueue) expire() {
+	q.next = time.Time{}
+	now := time.Now()
+	for len(q.heap) > 0 && !q.heap[0].when.After(now) {
+		t := heap.Pop(&q.heap).(*Timer)
+		if t.state.CompareAndSwap(timerPending, timerFired) {
+			t.fire()
+		}
+	}
+	q.reset()
+}
+
+// reset makes sure the time.Timer is set to wake the queue up when the earliest Timer is due.
+func (q *timerQueue) reset() {
+	if len(q.heap) == 0 {
+		if q.timer != nil && !q.next.IsZero() {
+			q.timer.Stop()
+			q.next = time.Time{}
+		}
+		return
+	}
+	when := q.heap[0].when
+	if when.Equal(q.next) {
+		return
+	}
+	q.next = when
+	if q.timer == nil {
+		q.timer = time.AfterFunc(time.Until(when), func() { q.Act(nil, q.expire) })
+		return
+	}
+	q.timer.Stop()
+	q.timer.Reset(time.Until(when))
+}
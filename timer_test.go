@@ -0,0 +1,118 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActAfter(t *testing.T) {
+	var a Inbox
+	results := make(chan int, 4)
+	for _, delay := range []int{30, 10, 20, 0} {
+		n := delay // Because delay gets mutated in place
+		a.ActAfter(nil, time.Duration(n)*time.Millisecond, func() { results <- n })
+	}
+	for _, expected := range []int{0, 10, 20, 30} {
+		if n := <-results; n != expected {
+			t.Errorf("got %d, expected %d", n, expected)
+		}
+	}
+}
+
+func TestActAfterCancel(t *testing.T) {
+	var a Inbox
+	ran := make(chan struct{}, 1)
+	timer := a.ActAfter(nil, 10*time.Millisecond, func() { ran <- struct{}{} })
+	if !timer.Cancel() {
+		t.Errorf("failed to cancel a pending timer")
+	}
+	if timer.Cancel() {
+		t.Errorf("cancelled a timer twice")
+	}
+	fired := a.ActAfter(nil, 0, func() { ran <- struct{}{} })
+	<-ran
+	if fired.Cancel() {
+		t.Errorf("cancelled a timer that already fired")
+	}
+	select {
+	case <-ran:
+		t.Errorf("cancelled timer fired")
+	case <-time.After(30 * time.Millisecond):
+	}
+	Block(&timers, func() {
+		if len(timers.heap) != 0 {
+			t.Errorf("%d timers left in the queue", len(timers.heap))
+		}
+	})
+}
+
+func TestActAfterBackpressure(t *testing.T) {
+	defer eagerBackpressure()()
+	var sender, receiver Inbox
+	// The receiver is idle when the timer is set, but busy when it fires
+	receiver.ActAfter(&sender, 10*time.Millisecond, func() {})
+	release := hold(&receiver)
+	for deadline := time.Now().Add(time.Second); sender.PendingBackpressure() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("sender never had backpressure applied")
+		}
+	}
+	ran := make(chan struct{})
+	sender.Act(nil, func() { close(ran) })
+	select {
+	case <-ran:
+		t.Errorf("sender didn't pause after the timer fired")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-ran
+}
+
+func TestActAfterSameDelay(t *testing.T) {
+	var a Inbox
+	var order []int
+	done := make(chan struct{})
+	const count = 16
+	for idx := 0; idx < count; idx++ {
+		n := idx // Because idx gets mutated in place
+		a.ActAfter(nil, 5*time.Millisecond, func() {
+			order = append(order, n)
+			if n == count-1 {
+				close(done)
+			}
+		})
+	}
+	<-done
+	for idx, n := range order {
+		if n != idx {
+			t.Fatalf("timers with the same delay fired out of order: %v", order)
+		}
+	}
+}
+
+func TestActAfterCancelRace(t *testing.T) {
+	var a Inbox
+	const count = 1000
+	cancelled := make([]bool, count)
+	ran := make([]bool, count)
+	for idx := 0; idx < count; idx++ {
+		n := idx // Because idx gets mutated in place
+		timer := a.ActAfter(nil, time.Duration(n%10)*time.Microsecond, func() { ran[n] = true })
+		if n%2 == 0 {
+			time.Sleep(time.Duration(n%7) * time.Microsecond)
+		}
+		cancelled[n] = timer.Cancel()
+	}
+	// Every timer has been cancelled or marked as fired by now, so once the queue finishes sending, every action that's going to run is already queued at a, ahead of the Block below
+	Block(&timers, func() {})
+	Block(&a, func() {
+		for idx := range ran {
+			if cancelled[idx] && ran[idx] {
+				t.Fatalf("timer %d ran after Cancel returned true", idx)
+			}
+			if !cancelled[idx] && !ran[idx] {
+				t.Fatalf("timer %d never ran, but Cancel returned false", idx)
+			}
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package phony
+
+import "sync/atomic"
+
+// Token is a lightweight signal for cooperatively cancelling long-running work.
+// The intended pattern is for a message to process one chunk of work, check the Token, and then send the next chunk to its own Inbox, so other messages (such as one that calls Cancel) can run in between.
+// This keeps cancellation within the actor model, without allocating a context.Context.
+// The zero value is an uncancelled Token, and a Token is safe for concurrent use.
+type Token struct {
+	cancelled atomic.Bool
+}
+
+// Cancel marks the Token as cancelled, it is safe to call more than once.
+func (t *Token) Cancel() {
+	t.cancelled.Store(true)
+}
+
+// Cancelled returns true if Cancel has been called.
+func (t *Token) Cancelled() bool {
+	return t.cancelled.Load()
+}
@@ -0,0 +1,26 @@
+package phony
+
+import "testing"
+
+func TestToken(t *testing.T) {
+	var a Inbox
+	var token Token
+	var chunks int
+	done := make(chan struct{})
+	var chunk func()
+	chunk = func() {
+		if token.Cancelled() {
+			close(done)
+			return
+		}
+		if chunks++; chunks == 10 {
+			a.Act(nil, token.Cancel)
+		}
+		a.Act(nil, chunk)
+	}
+	a.Act(nil, chunk)
+	<-done
+	if chunks != 10 {
+		t.Errorf("ran %d chunks, expected 10", chunks)
+	}
+}
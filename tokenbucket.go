@@ -0,0 +1,72 @@
+package phony
+
+import "time"
+
+// TokenBucket is an Actor that hands out tokens to requesters, in FIFO order, as they're replenished at a fixed rate.
+// It centralizes rate limiting for many Actors, and since tokens are granted by messages run by its own Inbox, the accounting needs no locks.
+type TokenBucket struct {
+	Inbox
+	rate    float64 // tokens per second
+	burst   float64
+	tokens  float64
+	last    time.Time
+	waiters []tokenRequest
+	timer   *time.Timer
+}
+
+type tokenRequest struct {
+	n    float64
+	then func()
+}
+
+// NewTokenBucket returns a new TokenBucket, which starts full, holds at most burst tokens, and replenishes rate tokens per second.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 || burst <= 0 {
+		panic("tried to create TokenBucket with non-positive rate or burst")
+	}
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Take sends a message to the TokenBucket, asking it to call then once n tokens have been granted.
+// Requests are granted strictly in the order they arrive, so a large request holds up smaller ones behind it.
+// The then function is run by the TokenBucket's Inbox, so it should not block, and would typically send a message to the requesting Actor.
+// Take panics if n is negative, since that would add tokens, or more than the bucket's burst size, since such a request could never be granted.
+func (b *TokenBucket) Take(from Actor, n int, then func()) {
+	if then == nil {
+		panic("tried to send nil action")
+	} else if n < 0 {
+		panic("tried to take a negative number of tokens")
+	} else if float64(n) > b.burst {
+		panic("tried to take more tokens than the burst size")
+	}
+	b.Act(from, func() {
+		b.waiters = append(b.waiters, tokenRequest{float64(n), then})
+		b.grant()
+	})
+}
+
+// grant hands out tokens to as many waiters as possible, and starts a timer to try again once the next waiter's tokens have been replenished.
+func (b *TokenBucket) grant() {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	for len(b.waiters) > 0 && b.tokens >= b.waiters[0].n {
+		w := b.waiters[0]
+		b.waiters[0] = tokenRequest{}
+		b.waiters = b.waiters[1:]
+		b.tokens -= w.n
+		w.then()
+	}
+	if len(b.waiters) > 0 && b.timer == nil {
+		wait := time.Duration((b.waiters[0].n - b.tokens) / b.rate * float64(time.Second))
+		b.timer = time.AfterFunc(wait, func() {
+			b.Act(nil, func() {
+				b.timer = nil
+				b.grant()
+			})
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package phony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(500, 1)
+	var requesters [10]Inbox
+	done := make(chan struct{}, 50)
+	start := time.Now()
+	for idx := range requesters {
+		r := &requesters[idx]
+		Block(r, func() {
+			for n := 0; n < 5; n++ {
+				b.Take(r, 1, func() { done <- struct{}{} })
+			}
+		})
+	}
+	for idx := 0; idx < 50; idx++ {
+		<-done
+	}
+	// The first token is available immediately, the other 49 take 2ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Errorf("granted 50 tokens in %v, expected about 98ms", elapsed)
+	}
+}
+
+func TestTokenBucketOrder(t *testing.T) {
+	b := NewTokenBucket(1000, 4)
+	var order []int
+	done := make(chan struct{})
+	for idx, n := range []int{4, 3, 1, 2} {
+		idx := idx // Because idx gets mutated in place
+		b.Take(nil, n, func() {
+			if order = append(order, idx); len(order) == 4 {
+				close(done)
+			}
+		})
+	}
+	<-done
+	for idx, n := range order {
+		if n != idx {
+			t.Errorf("request %d granted at position %d", n, idx)
+		}
+	}
+}
+
+func TestTokenBucketNegative(t *testing.T) {
+	b := NewTokenBucket(1, 1)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic for a negative number of tokens")
+		}
+	}()
+	b.Take(nil, -1, func() {})
+}
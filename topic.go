@@ -0,0 +1,56 @@
+package phony
+
+// Topic is an Actor that delivers each published value of type T to every subscriber.
+// Subscriptions are managed by messages run by the Topic's own Inbox, so they're ordered with respect to Publish.
+// The zero value is ready to use.
+type Topic[T any] struct {
+	Inbox
+	subs []*Subscription[T]
+}
+
+// Subscription is a handle returned by Topic.Subscribe, which can be used to unsubscribe.
+type Subscription[T any] struct {
+	topic   *Topic[T]
+	actor   Actor
+	handler func(T)
+}
+
+// Subscribe sends a message to the Topic, asking it to deliver values to handler.
+// Each value is delivered by sending a message to the subscribing Actor, which runs handler.
+func (t *Topic[T]) Subscribe(actor Actor, handler func(T)) *Subscription[T] {
+	if actor == nil {
+		panic("tried to subscribe nil actor")
+	} else if handler == nil {
+		panic("tried to subscribe nil handler")
+	}
+	s := &Subscription[T]{topic: t, actor: actor, handler: handler}
+	t.Act(nil, func() {
+		t.subs = append(t.subs, s)
+	})
+	return s
+}
+
+// Unsubscribe sends a message to the Topic, asking it to stop delivering values to the subscription.
+// Values which were already sent to the subscribing Actor are still handled.
+func (s *Subscription[T]) Unsubscribe() {
+	t := s.topic
+	t.Act(nil, func() {
+		for idx, sub := range t.subs {
+			if sub == s {
+				t.subs = append(t.subs[:idx], t.subs[idx+1:]...)
+				break
+			}
+		}
+	})
+}
+
+// Publish sends a message to the Topic, asking it to deliver value to every subscriber.
+// The Topic sends to subscribers as itself, so a flooded subscriber applies backpressure to the Topic, which in turn applies backpressure to publishers.
+func (t *Topic[T]) Publish(from Actor, value T) {
+	t.Act(from, func() {
+		for _, s := range t.subs {
+			handler := s.handler
+			s.actor.Act(t, func() { handler(value) })
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package phony
+
+import "testing"
+
+func TestTopic(t *testing.T) {
+	type event struct {
+		n    int
+		name string
+	}
+	var topic Topic[event]
+	var subscribers [4]Inbox
+	var results [4][]event
+	var subs [4]*Subscription[event]
+	for idx := range subscribers {
+		idx := idx // Because idx gets mutated in place
+		subs[idx] = topic.Subscribe(&subscribers[idx], func(e event) {
+			results[idx] = append(results[idx], e)
+		})
+	}
+	for idx := 0; idx < 100; idx++ {
+		if idx == 50 {
+			subs[3].Unsubscribe()
+		}
+		topic.Publish(nil, event{idx, "event"})
+	}
+	Block(&topic, func() {})
+	for idx := range subscribers {
+		Block(&subscribers[idx], func() {})
+	}
+	for idx, events := range results {
+		expected := 100
+		if idx == 3 {
+			expected = 50
+		}
+		if len(events) != expected {
+			t.Errorf("subscriber %d got %d events, expected %d", idx, len(events), expected)
+		}
+		for n, e := range events {
+			if e.n != n || e.name != "event" {
+				t.Errorf("subscriber %d got event %v at index %d", idx, e, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package phony
+
+import "sync/atomic"
+
+// Var is an Actor that owns an observable value of type T.
+// Each Set replaces the value and notifies every watcher, on the watcher's own Actor.
+// A watcher that falls behind only sees the latest value when it catches up, so a slow watcher never holds up the Var or queues a backlog of stale values.
+type Var[T any] struct {
+	Inbox
+	value    T
+	watchers []*varWatcher[T]
+}
+
+// varWatcher holds the latest value that hasn't been delivered to a watcher yet, if any.
+type varWatcher[T any] struct {
+	observer Actor
+	onChange func(T)
+	latest   atomic.Pointer[T]
+}
+
+// NewVar returns a new Var holding the provided value.
+func NewVar[T any](value T) *Var[T] {
+	return &Var[T]{value: value}
+}
+
+// Set sends a message to the Var, asking it to store value and then notify its watchers.
+func (v *Var[T]) Set(from Actor, value T) {
+	v.Act(from, func() {
+		v.value = value
+		for _, w := range v.watchers {
+			w.notify(value)
+		}
+	})
+}
+
+// Get returns a copy of the Var's value.
+// It uses Block, so it must not be called from an Actor.
+func (v *Var[T]) Get() T {
+	var value T
+	Block(v, func() { value = v.value })
+	return value
+}
+
+// Watch registers onChange to be run on the observer's Inbox with each value stored by a later Set.
+// Values are seen in the order they were set, but intermediate values are skipped if the observer is still busy when newer ones arrive.
+func (v *Var[T]) Watch(observer Actor, onChange func(T)) {
+	if observer == nil {
+		panic("tried to send to nil actor")
+	} else if onChange == nil {
+		panic("tried to send nil action")
+	}
+	w := &varWatcher[T]{observer: observer, onChange: onChange}
+	v.Act(nil, func() {
+		v.watchers = append(v.watchers, w)
+	})
+}
+
+// notify hands value to the watcher, only sending a message if one isn't already pending, it must only be called from the Var's own messages.
+func (w *varWatcher[T]) notify(value T) {
+	if w.latest.Swap(&value) != nil {
+		// A message is already pending, and will pick up this value instead
+		return
+	}
+	w.observer.Act(nil, func() {
+		w.onChange(*w.latest.Swap(nil))
+	})
+}
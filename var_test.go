@@ -0,0 +1,40 @@
+package phony
+
+import "testing"
+
+func TestVar(t *testing.T) {
+	const count = 1024
+	v := NewVar(0)
+	type watcher struct {
+		Inbox
+		seen []int
+		done chan struct{}
+	}
+	watchers := make([]*watcher, 4)
+	for idx := range watchers {
+		w := &watcher{done: make(chan struct{})}
+		watchers[idx] = w
+		v.Watch(w, func(value int) {
+			w.seen = append(w.seen, value)
+			if value == count {
+				close(w.done)
+			}
+		})
+	}
+	for idx := 1; idx <= count; idx++ {
+		v.Set(nil, idx)
+	}
+	for _, w := range watchers {
+		<-w.done
+		Block(w, func() {
+			for idx := 1; idx < len(w.seen); idx++ {
+				if w.seen[idx] <= w.seen[idx-1] {
+					t.Errorf("saw %d after %d", w.seen[idx], w.seen[idx-1])
+				}
+			}
+		})
+	}
+	if value := v.Get(); value != count {
+		t.Errorf("got %d, expected %d", value, count)
+	}
+}